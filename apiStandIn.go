@@ -0,0 +1,252 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// StandInUpload is a file uploaded to the stand-in API
+type StandInUpload struct {
+	EntityId     string `json:"entityId"`
+	Type         string `json:"type"`
+	Mime         string `json:"mime"`
+	Deployment   string `json:"deployment,omitempty"`
+	Platform     string `json:"platform,omitempty"`
+	OriginalPath string `json:"originalPath,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// standInApi is a local stand-in of the APIv2 endpoints used by the job processing, it hands out the queued jobs and records the job updates
+type standInApi struct {
+	server   *httptest.Server
+	filesDir string // Served at /files/, directories are served zipped at /files/<dir>.zip
+
+	mu            sync.Mutex
+	jobs          []JobMetadata
+	Statuses      []JobStatusRequestMetadata
+	Reports       []JobLogRequestMetadata
+	StreamedLines int
+	Uploads       []StandInUpload
+}
+
+// startStandInApi starts the stand-in API at a local address, jobs are handed out in order
+func startStandInApi(filesDir string, jobs ...JobMetadata) *standInApi {
+	a := &standInApi{filesDir: filesDir, jobs: jobs}
+	a.server = httptest.NewServer(http.HandlerFunc(a.handle))
+	return a
+}
+
+// Url returns the base URL of the stand-in API
+func (a *standInApi) Url() string {
+	return a.server.URL
+}
+
+// Close stops the stand-in API
+func (a *standInApi) Close() {
+	a.server.Close()
+}
+
+// LastStatus returns the last job status update
+func (a *standInApi) LastStatus() (status JobStatusRequestMetadata) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.Statuses) > 0 {
+		status = a.Statuses[len(a.Statuses)-1]
+	}
+
+	return
+}
+
+// handle routes the requests to the stand-in endpoints
+func (a *standInApi) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodPost && path == "auth/login":
+		writeStandInJson(w, http.StatusOK, map[string]string{"status": "ok", "data": "stand-in-token"})
+	case r.Method == http.MethodGet && path == "jobs/unclaimed":
+		a.handleUnclaimedJob(w)
+	case r.Method == http.MethodPatch && len(parts) == 3 && parts[0] == "jobs" && parts[2] == "status":
+		var status JobStatusRequestMetadata
+		if a.decode(w, r, &status) {
+			a.mu.Lock()
+			a.Statuses = append(a.Statuses, status)
+			a.mu.Unlock()
+			writeStandInJson(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "jobs" && parts[2] == "log":
+		var report JobLogRequestMetadata
+		if a.decode(w, r, &report) {
+			a.mu.Lock()
+			a.Reports = append(a.Reports, report)
+			a.mu.Unlock()
+			writeStandInJson(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[0] == "jobs" && parts[2] == "log" && parts[3] == "stream":
+		var batch JobLogStreamRequestMetadata
+		if a.decode(w, r, &batch) {
+			a.mu.Lock()
+			a.StreamedLines += len(batch.Lines)
+			a.mu.Unlock()
+			writeStandInJson(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	case r.Method == http.MethodPut && len(parts) == 4 && parts[0] == "entities" && parts[2] == "files" && parts[3] == "upload":
+		a.handleUpload(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) > 1 && parts[0] == "files":
+		a.handleFile(w, r, strings.Join(parts[1:], "/"))
+	default:
+		writeStandInJson(w, http.StatusNotFound, map[string]string{"status": "error", "message": fmt.Sprintf("%s %s is not supported by the stand-in API", r.Method, r.URL.Path)})
+	}
+}
+
+// handleUnclaimedJob hands out the next queued job
+func (a *standInApi) handleUnclaimedJob(w http.ResponseWriter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.jobs) == 0 {
+		writeStandInJson(w, http.StatusOK, map[string]string{"status": "no jobs"})
+		return
+	}
+
+	job := a.jobs[0]
+	a.jobs = a.jobs[1:]
+
+	writeStandInJson(w, http.StatusOK, JobMetadataContainer{JobMetadata: job, Status: "ok"})
+}
+
+// handleUpload records the uploaded multipart file
+func (a *standInApi) handleUpload(w http.ResponseWriter, r *http.Request, entityId string) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeStandInJson(w, http.StatusBadRequest, map[string]string{"status": "error", "message": err.Error()})
+		return
+	}
+
+	query := r.URL.Query()
+	upload := StandInUpload{
+		EntityId:     entityId,
+		Type:         query.Get("type"),
+		Mime:         query.Get("mime"),
+		Deployment:   query.Get("deployment"),
+		Platform:     query.Get("platform"),
+		OriginalPath: query.Get("original-path"),
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			writeStandInJson(w, http.StatusBadRequest, map[string]string{"status": "error", "message": err.Error()})
+			return
+		}
+
+		n, err := io.Copy(io.Discard, part)
+		if err != nil {
+			writeStandInJson(w, http.StatusBadRequest, map[string]string{"status": "error", "message": err.Error()})
+			return
+		}
+
+		if part.FormName() == "file" {
+			upload.Size = n
+		}
+	}
+
+	a.mu.Lock()
+	a.Uploads = append(a.Uploads, upload)
+	a.mu.Unlock()
+
+	writeStandInJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleFile serves a file of the files directory, a directory is served as a zip archive if requested with the .zip extension
+func (a *standInApi) handleFile(w http.ResponseWriter, r *http.Request, name string) {
+	path := filepath.Join(a.filesDir, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, filepath.Clean(a.filesDir)+string(filepath.Separator)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	dir := strings.TrimSuffix(path, ".zip")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() || dir == path {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	if err := writeDirZip(w, dir); err != nil {
+		Logger.Errorf("failed to zip %s: %v", dir, err)
+	}
+}
+
+// decode parses the JSON request body, responds with an error if it is invalid
+func (a *standInApi) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeStandInJson(w, http.StatusBadRequest, map[string]string{"status": "error", "message": err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// writeStandInJson writes the JSON response
+func writeStandInJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Logger.Errorf("failed to write the stand-in API response: %v", err)
+	}
+}
+
+// writeDirZip writes the directory files as a zip archive with paths relative to the directory
+func writeDirZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fw, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(fw, f)
+		if err1 := f.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
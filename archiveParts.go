@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parseByteSize parses a size such as "5368709120", "5GiB" or "512MB" into bytes
+func parseByteSize(s string) (int64, error) {
+	var units = []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+		{"B", 1},
+	}
+
+	s = strings.TrimSpace(s)
+	var multiplier int64 = 1
+	for _, u := range units {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(u.suffix)) {
+			s = strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			multiplier = u.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %v", s, err)
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("invalid negative size %d", n)
+	}
+
+	return n * multiplier, nil
+}
+
+// formatByteSize formats a size in bytes using binary units, e.g. "1.5 GiB"
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// indexReleaseArchive describes the numbered parts (<archive>.001, <archive>.002, ...) of at most partSize bytes the archive is uploaded as,
+// the parts are read from the archive itself so they take no additional disk space
+func indexReleaseArchive(path string, partSize int64) (index ReleaseArchiveIndex, err error) {
+	if partSize <= 0 {
+		return index, fmt.Errorf("invalid part size %d", partSize)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return index, fmt.Errorf("failed to open an archive: %v", err)
+	}
+	defer func(in *os.File) {
+		if err := in.Close(); err != nil {
+			Logger.Errorf("failed to close an archive: %v", err)
+		}
+	}(in)
+
+	fi, err := in.Stat()
+	if err != nil {
+		return index, fmt.Errorf("failed to stat an archive: %v", err)
+	}
+
+	total := int((fi.Size() + partSize - 1) / partSize)
+	if total == 0 {
+		total = 1
+	}
+
+	index = ReleaseArchiveIndex{
+		Name:     filepath.Base(path),
+		Size:     fi.Size(),
+		PartSize: partSize,
+		Method:   "concat",
+	}
+
+	archiveHash := sha256.New()
+	for i := 1; i <= total; i++ {
+		partHash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(archiveHash, partHash), io.LimitReader(in, partSize))
+		if err != nil {
+			return index, fmt.Errorf("failed to read an archive part %d: %v", i, err)
+		}
+
+		index.Parts = append(index.Parts, ReleaseArchivePart{
+			Index:  i,
+			Name:   fmt.Sprintf("%s.%03d", filepath.Base(path), i),
+			Size:   size,
+			Sha256: hex.EncodeToString(partHash.Sum(nil)),
+		})
+	}
+
+	index.Sha256 = hex.EncodeToString(archiveHash.Sum(nil))
+
+	return index, nil
+}
+
+// uploadReleaseArchive uploads the release archive, splitting it into parts with an index document if it exceeds the configured part size
+func uploadReleaseArchive(job JobMetadata, path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat a release archive: %v", err)
+	}
+
+	if releaseArchivePartSize <= 0 || fi.Size() <= releaseArchivePartSize {
+		return uploadReleaseArchiveFile(job, path, filepath.Base(path), nil)
+	}
+
+	Logger.Infof("splitting release archive %s of size %d into parts of %d bytes", path, fi.Size(), releaseArchivePartSize)
+
+	index, err := indexReleaseArchive(path, releaseArchivePartSize)
+	if err != nil {
+		return fmt.Errorf("failed to split a release archive: %v", err)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open a release archive: %v", err)
+	}
+	defer func(in *os.File) {
+		if err := in.Close(); err != nil {
+			Logger.Errorf("failed to close a release archive: %v", err)
+		}
+	}(in)
+
+	for _, part := range index.Parts {
+		params := map[string]string{
+			"index": strconv.Itoa(part.Index),
+			"total": strconv.Itoa(len(index.Parts)),
+		}
+		section := io.NewSectionReader(in, int64(part.Index-1)*index.PartSize, part.Size)
+		if err = uploadReleaseArchivePart(job, section, part.Size, part.Name, params); err != nil {
+			return fmt.Errorf("failed to upload a release archive part %d/%d: %v", part.Index, len(index.Parts), err)
+		}
+	}
+
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize a release archive index: %v", err)
+	}
+
+	if err = uploadReleaseArchiveIndex(job, b, filepath.Base(path)+".json", nil); err != nil {
+		return fmt.Errorf("failed to upload a release archive index: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IniOverride overrides a config value for the UAT run, rendered as -ini:<File>:[<Section>]:<Key>=<Value>
+type IniOverride struct {
+	File    string `json:"file"`    // Config file name without the prefix, e.g. Game or Engine
+	Section string `json:"section"` // Config section, e.g. /Script/UnrealEd.ProjectPackagingSettings
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+// String renders the override as a UAT argument
+func (o IniOverride) String() string {
+	return fmt.Sprintf("-ini:%s:[%s]:%s=%s", o.File, o.Section, o.Key, o.Value)
+}
+
+// BuildCookRun is the typed UAT BuildCookRun command, rendered to argv by Args so values with spaces stay single arguments
+type BuildCookRun struct {
+	Project   string // Project name or path to the .uproject file
+	UnrealExe string // Path to the editor used to cook
+
+	Client          bool     // Build the client, -noclient is passed otherwise
+	ClientConfig    string   // Client configuration, e.g. Shipping
+	Platforms       []string // Client platforms
+	Server          bool     // Build the dedicated server
+	ServerConfig    string   // Server configuration, e.g. Shipping
+	ServerPlatforms []string // Server platforms
+
+	Maps []string // Maps to cook, the project default maps are cooked if empty
+
+	Build                    bool   // Compile the targets
+	Cook                     bool   // Cook the content
+	UnversionedCookedContent bool   // Omit engine versions from cooked packages
+	SkipCookingEditorContent bool   // Do not cook editor only content
+	Pak                      bool   // Put the cooked content into pak files
+	Compressed               bool   // Compress pak files
+	Package                  bool   // Package the staged build for the platform
+	Stage                    bool   // Stage the build into StagingDirectory
+	SkipStage                bool   // Use the cooked data from the previous stage
+	StagingDirectory         string // Directory to stage the build to
+
+	CreateReleaseVersion  string // Create a release version used as a base for patches and DLC
+	BasedOnReleaseVersion string // Release version the DLC is based on
+
+	DLCName                 string // Name of the DLC plugin to cook
+	DLCIncludeEngineContent bool   // Include engine content referenced by the DLC
+
+	Distribution  bool // Distribution build
+	Prereqs       bool // Stage the prerequisites installer
+	CrashReporter bool // Stage the crash reporter
+	NoDebugInfo   bool // Do not stage debug info
+	NoDebug       bool // Do not stage debug files
+	Debug         bool // Stage debug files
+
+	Ini []IniOverride // Config overrides
+
+	NoP4                     bool // Do not use Perforce
+	NoCodeSign               bool // Skip code signing
+	BuildMachine             bool // Run as a build machine
+	AllowCommandletRendering bool // Allow rendering in commandlets
+	UTF8Output               bool // Write UTF-8 output
+	VeryVerbose              bool // Very verbose logging
+
+	ExtraArgs []string // Additional arguments appended as is
+}
+
+// Args validates the command and renders it to the UAT argument list
+func (c BuildCookRun) Args() ([]string, error) {
+	if c.Project == "" {
+		return nil, fmt.Errorf("BuildCookRun requires a project")
+	}
+
+	if !c.Client && !c.Server {
+		return nil, fmt.Errorf("BuildCookRun requires a client or a server target")
+	}
+
+	if c.Client && len(c.Platforms) == 0 {
+		return nil, fmt.Errorf("BuildCookRun client requires a platform")
+	}
+
+	if c.Server && len(c.ServerPlatforms) == 0 {
+		return nil, fmt.Errorf("BuildCookRun server requires a platform")
+	}
+
+	if c.Stage && c.SkipStage {
+		return nil, fmt.Errorf("BuildCookRun can not both stage and skip stage")
+	}
+
+	if c.StagingDirectory != "" && !c.Stage {
+		return nil, fmt.Errorf("BuildCookRun staging directory requires stage")
+	}
+
+	if c.DLCIncludeEngineContent && c.DLCName == "" {
+		return nil, fmt.Errorf("BuildCookRun DLC options require a DLC name")
+	}
+
+	if c.Debug && (c.NoDebug || c.NoDebugInfo) {
+		return nil, fmt.Errorf("BuildCookRun can not both stage and skip debug files")
+	}
+
+	args := []string{"BuildCookRun", "-project=" + c.Project}
+
+	if c.NoP4 {
+		args = append(args, "-noP4")
+	}
+
+	if c.UnrealExe != "" {
+		args = append(args, "-unrealexe="+c.UnrealExe)
+	}
+
+	if c.Client {
+		if c.ClientConfig != "" {
+			args = append(args, "-clientconfig="+c.ClientConfig)
+		}
+		args = append(args, "-platform="+strings.Join(c.Platforms, "+"))
+	} else {
+		args = append(args, "-noclient")
+	}
+
+	if c.Server {
+		args = append(args, "-server")
+		if c.ServerConfig != "" {
+			args = append(args, "-serverconfig="+c.ServerConfig)
+		}
+		args = append(args, "-serverplatform="+strings.Join(c.ServerPlatforms, "+"))
+	}
+
+	for _, o := range c.Ini {
+		args = append(args, o.String())
+	}
+
+	args = appendFlag(args, c.Build, "-build")
+	args = appendFlag(args, c.Cook, "-cook")
+	args = appendFlag(args, c.UnversionedCookedContent, "-unversionedcookedcontent")
+	args = appendFlag(args, c.SkipCookingEditorContent, "-SkipCookingEditorContent")
+
+	if len(c.Maps) > 0 {
+		args = append(args, "-map="+strings.Join(c.Maps, "+"))
+	}
+
+	args = appendFlag(args, c.Pak, "-pak")
+
+	if c.DLCName != "" {
+		args = append(args, "-dlcname="+c.DLCName)
+	}
+	args = appendFlag(args, c.DLCIncludeEngineContent, "-DLCIncludeEngineContent")
+
+	if c.BasedOnReleaseVersion != "" {
+		args = append(args, "-basedonreleaseversion="+c.BasedOnReleaseVersion)
+	}
+
+	args = appendFlag(args, c.Compressed, "-compressed")
+	args = appendFlag(args, c.Package, "-package")
+
+	if c.CreateReleaseVersion != "" {
+		args = append(args, "-createreleaseversion="+c.CreateReleaseVersion)
+	}
+
+	args = appendFlag(args, c.Stage, "-stage")
+	args = appendFlag(args, c.SkipStage, "-skipstage")
+
+	if c.StagingDirectory != "" {
+		args = append(args, "-stagingdirectory="+c.StagingDirectory)
+	}
+
+	args = appendFlag(args, c.Distribution, "-distribution")
+	args = appendFlag(args, c.VeryVerbose, "-VeryVerbose")
+	args = appendFlag(args, c.NoCodeSign, "-NoCodeSign")
+	args = appendFlag(args, c.BuildMachine, "-BuildMachine")
+	args = appendFlag(args, c.AllowCommandletRendering, "-AllowCommandletRendering")
+	args = appendFlag(args, c.UTF8Output, "-utf8output")
+	args = appendFlag(args, c.CrashReporter, "-CrashReporter")
+	args = appendFlag(args, c.NoDebug, "-nodebug")
+	args = appendFlag(args, c.NoDebugInfo, "-nodebuginfo")
+	args = appendFlag(args, c.Prereqs, "-prereqs")
+	args = appendFlag(args, c.Debug, "-debug")
+
+	args = append(args, c.ExtraArgs...)
+
+	return args, nil
+}
+
+// BuildEditor is the typed UAT BuildEditor command
+type BuildEditor struct {
+	Project   string   // Project name or path to the .uproject file
+	ExtraArgs []string // Additional arguments appended as is
+}
+
+// Args validates the command and renders it to the UAT argument list
+func (c BuildEditor) Args() ([]string, error) {
+	if c.Project == "" {
+		return nil, fmt.Errorf("BuildEditor requires a project")
+	}
+
+	return append([]string{"BuildEditor", "-project=" + c.Project}, c.ExtraArgs...), nil
+}
+
+// RunUnreal is the typed UAT RunUnreal command running a Gauntlet test, e.g. the editor automation tests
+type RunUnreal struct {
+	Project          string // Project name or path to the .uproject file
+	Platform         string // Platform of the build to run
+	Configuration    string // Configuration of the build to run, e.g. Development
+	Build            string // Build to run, "editor" runs the editor
+	Test             string // Gauntlet test, e.g. UE.EditorAutomation
+	RunTest          string // Automation test filter passed to "Automation RunTests", e.g. Project
+	ReportExportPath string // Directory the automation test report is exported to
+	NullRHI          bool   // Run without rendering
+	Unattended       bool   // Do not show dialogs
+	NoP4             bool   // Do not use Perforce
+	UTF8Output       bool   // Write UTF-8 output
+
+	ExtraArgs []string // Additional arguments appended as is
+}
+
+// Args validates the command and renders it to the UAT argument list
+func (c RunUnreal) Args() ([]string, error) {
+	if c.Project == "" {
+		return nil, fmt.Errorf("RunUnreal requires a project")
+	}
+
+	if c.Test == "" {
+		return nil, fmt.Errorf("RunUnreal requires a test")
+	}
+
+	args := []string{"RunUnreal", "-project=" + c.Project}
+
+	if c.Platform != "" {
+		args = append(args, "-platform="+c.Platform)
+	}
+
+	if c.Configuration != "" {
+		args = append(args, "-configuration="+c.Configuration)
+	}
+
+	if c.Build != "" {
+		args = append(args, "-build="+c.Build)
+	}
+
+	args = append(args, "-test="+c.Test)
+
+	if c.RunTest != "" {
+		args = append(args, "-RunTest="+c.RunTest)
+	}
+
+	if c.ReportExportPath != "" {
+		args = append(args, "-ReportExportPath="+c.ReportExportPath)
+	}
+
+	args = appendFlag(args, c.NullRHI, "-nullrhi")
+	args = appendFlag(args, c.Unattended, "-unattended")
+	args = appendFlag(args, c.NoP4, "-noP4")
+	args = appendFlag(args, c.UTF8Output, "-utf8output")
+
+	args = append(args, c.ExtraArgs...)
+
+	return args, nil
+}
+
+// appendFlag appends the flag if it is set
+func appendFlag(args []string, set bool, flag string) []string {
+	if set {
+		return append(args, flag)
+	}
+
+	return args
+}
+
+// disableBlueprintNativization is the config override used by all the release builds
+var disableBlueprintNativization = IniOverride{
+	File:    "Game",
+	Section: "/Script/UnrealEd.ProjectPackagingSettings",
+	Key:     "BlueprintNativizationMethod",
+	Value:   "Disabled",
+}
+
+// newReleaseBuildCookRun creates the command building, cooking and staging the full release of the job platform and deployment
+func newReleaseBuildCookRun(job JobMetadata, stagingDir string) BuildCookRun {
+	c := BuildCookRun{
+		Project:                  projectName,
+		UnrealExe:                editorPath,
+		Ini:                      []IniOverride{disableBlueprintNativization},
+		Build:                    true,
+		Cook:                     true,
+		UnversionedCookedContent: true,
+		SkipCookingEditorContent: true,
+		Maps:                     []string{job.Release.Map},
+		Pak:                      true,
+		Compressed:               true,
+		Package:                  true,
+		CreateReleaseVersion:     job.Release.ContentVersion,
+		Stage:                    true,
+		StagingDirectory:         stagingDir,
+		NoP4:                     true,
+		NoCodeSign:               true,
+		BuildMachine:             true,
+		AllowCommandletRendering: true,
+		UTF8Output:               true,
+		VeryVerbose:              true,
+	}
+
+	c.setTarget(job, job.Configuration)
+
+	return c
+}
+
+// newPackageBuildCookRun creates the command cooking the job package as a DLC based on the package release
+func newPackageBuildCookRun(job JobMetadata) BuildCookRun {
+	c := BuildCookRun{
+		Project:                  projectName,
+		UnrealExe:                editorPath,
+		Cook:                     true,
+		Maps:                     []string{job.Package.Map},
+		UnversionedCookedContent: true,
+		Pak:                      true,
+		DLCName:                  job.Package.Name,
+		DLCIncludeEngineContent:  true,
+		BasedOnReleaseVersion:    job.Package.Release,
+		Compressed:               true,
+		Package:                  true,
+		SkipStage:                true,
+		NoP4:                     true,
+		BuildMachine:             true,
+		UTF8Output:               true,
+		VeryVerbose:              true,
+	}
+
+	c.setTarget(job, job.Configuration)
+
+	return c
+}
+
+// setTarget selects the client or the server target of the job deployment
+func (c *BuildCookRun) setTarget(job JobMetadata, configuration string) {
+	if job.Deployment == "Server" {
+		c.Client = false
+		c.Server = true
+		c.ServerConfig = configuration
+		c.ServerPlatforms = []string{job.Platform}
+	} else {
+		c.Client = true
+		c.ClientConfig = configuration
+		c.Platforms = []string{job.Platform}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withBuildGlobals sets the project name and the editor path used by the command constructors for the test
+func withBuildGlobals(t *testing.T, name string, editor string) {
+	t.Helper()
+
+	oldName, oldEditor := projectName, editorPath
+	projectName, editorPath = name, editor
+	t.Cleanup(func() {
+		projectName, editorPath = oldName, oldEditor
+	})
+}
+
+func TestBuildCookRunArgs(t *testing.T) {
+	c := BuildCookRun{
+		Project:         "C:/Projects/My Project/Metaverse.uproject",
+		UnrealExe:       "C:/Program Files/Epic Games/UE_5.1/Engine/Binaries/Win64/UnrealEditor-Cmd.exe",
+		Client:          true,
+		ClientConfig:    "Shipping",
+		Platforms:       []string{"Win64", "Linux"},
+		Server:          true,
+		ServerConfig:    "Development",
+		ServerPlatforms: []string{"Linux"},
+		Maps:            []string{"/Game/Maps/Lobby", "/Game/Maps/Forest Glade"},
+		Ini: []IniOverride{
+			{File: "Game", Section: "/Script/EngineSettings.GeneralProjectSettings", Key: "ProjectName", Value: "My Project"},
+		},
+		Cook:             true,
+		Pak:              true,
+		Stage:            true,
+		StagingDirectory: "C:/Projects/My Project/Saved/Staged Builds",
+		ExtraArgs:        []string{"-iterate"},
+	}
+
+	got, err := c.Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"BuildCookRun",
+		"-project=C:/Projects/My Project/Metaverse.uproject",
+		"-unrealexe=C:/Program Files/Epic Games/UE_5.1/Engine/Binaries/Win64/UnrealEditor-Cmd.exe",
+		"-clientconfig=Shipping",
+		"-platform=Win64+Linux",
+		"-server",
+		"-serverconfig=Development",
+		"-serverplatform=Linux",
+		"-ini:Game:[/Script/EngineSettings.GeneralProjectSettings]:ProjectName=My Project",
+		"-cook",
+		"-map=/Game/Maps/Lobby+/Game/Maps/Forest Glade",
+		"-pak",
+		"-stage",
+		"-stagingdirectory=C:/Projects/My Project/Saved/Staged Builds",
+		"-iterate",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestBuildCookRunArgsInvalid(t *testing.T) {
+	client := BuildCookRun{Project: "Metaverse", Client: true, Platforms: []string{"Linux"}}
+
+	tests := []struct {
+		name    string
+		command func() BuildCookRun
+		err     string
+	}{
+		{"no project", func() BuildCookRun { c := client; c.Project = ""; return c }, "requires a project"},
+		{"no target", func() BuildCookRun { c := client; c.Client = false; return c }, "requires a client or a server target"},
+		{"no client platform", func() BuildCookRun { c := client; c.Platforms = nil; return c }, "client requires a platform"},
+		{"no server platform", func() BuildCookRun { c := client; c.Server = true; return c }, "server requires a platform"},
+		{"stage and skip stage", func() BuildCookRun { c := client; c.Stage, c.SkipStage = true, true; return c }, "can not both stage and skip stage"},
+		{"staging directory without stage", func() BuildCookRun { c := client; c.StagingDirectory = "Staged"; return c }, "staging directory requires stage"},
+		{"DLC options without name", func() BuildCookRun { c := client; c.DLCIncludeEngineContent = true; return c }, "require a DLC name"},
+		{"debug and no debug", func() BuildCookRun { c := client; c.Debug, c.NoDebug = true, true; return c }, "skip debug files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.command().Args()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Args() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBuildEditorArgs(t *testing.T) {
+	got, err := BuildEditor{Project: "D:/Unreal Projects/Metaverse/Metaverse.uproject", ExtraArgs: []string{"-notools"}}.Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"BuildEditor", "-project=D:/Unreal Projects/Metaverse/Metaverse.uproject", "-notools"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+
+	if _, err = (BuildEditor{}).Args(); err == nil {
+		t.Error("expected an error without a project")
+	}
+}
+
+func TestNewReleaseBuildCookRun(t *testing.T) {
+	withBuildGlobals(t, "Metaverse", "/opt/Unreal Engine/Engine/Binaries/Linux/UnrealEditor-Cmd")
+
+	name := "Metaverse"
+	job := JobMetadata{
+		Platform:      "Linux",
+		Deployment:    "Server",
+		Configuration: "Shipping",
+		Release:       &Release{Map: "/Game/Maps/Lobby", ContentVersion: "1.2.0", Name: &name},
+	}
+
+	got, err := newReleaseBuildCookRun(job, "/home/builder/My Project/Saved/StagedBuilds").Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"BuildCookRun",
+		"-project=Metaverse",
+		"-noP4",
+		"-unrealexe=/opt/Unreal Engine/Engine/Binaries/Linux/UnrealEditor-Cmd",
+		"-noclient",
+		"-server",
+		"-serverconfig=Shipping",
+		"-serverplatform=Linux",
+		"-ini:Game:[/Script/UnrealEd.ProjectPackagingSettings]:BlueprintNativizationMethod=Disabled",
+		"-build",
+		"-cook",
+		"-unversionedcookedcontent",
+		"-SkipCookingEditorContent",
+		"-map=/Game/Maps/Lobby",
+		"-pak",
+		"-compressed",
+		"-package",
+		"-createreleaseversion=1.2.0",
+		"-stage",
+		"-stagingdirectory=/home/builder/My Project/Saved/StagedBuilds",
+		"-VeryVerbose",
+		"-NoCodeSign",
+		"-BuildMachine",
+		"-AllowCommandletRendering",
+		"-utf8output",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestNewReleaseBuildCookRunClient(t *testing.T) {
+	withBuildGlobals(t, "Metaverse", "UnrealEditor-Cmd.exe")
+
+	job := JobMetadata{
+		Platform:      "Win64",
+		Deployment:    "Client",
+		Configuration: "Development",
+		Release:       &Release{Map: "/Game/Maps/Lobby", ContentVersion: "1.2.0"},
+	}
+
+	got, err := newReleaseBuildCookRun(job, "Staged").Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"-clientconfig=Development", "-platform=Win64"}
+	if !reflect.DeepEqual(got[4:6], want) {
+		t.Errorf("client target = %q, want %q", got[4:6], want)
+	}
+
+	for _, arg := range got {
+		if arg == "-noclient" || arg == "-server" || strings.HasPrefix(arg, "-serverconfig") {
+			t.Errorf("unexpected server argument %s in %q", arg, got)
+		}
+	}
+}
+
+// The SDK command used to pass the misspelled -serveconfig=Development along with the client configuration
+func TestNewReleaseBuildCookRunSdk(t *testing.T) {
+	withBuildGlobals(t, "Metaverse", "UnrealEditor-Cmd.exe")
+
+	job := JobMetadata{
+		Platform:      "Win64",
+		Deployment:    "SDK",
+		Configuration: "Shipping",
+		Release:       &Release{Map: "/Game/Maps/Lobby", ContentVersion: "1.2.0"},
+	}
+
+	command := newReleaseBuildCookRun(job, "C:/Metaverse Project/Saved/StagedBuilds")
+	command.setTarget(job, "Development")
+	command.NoDebug = true
+	command.NoDebugInfo = true
+
+	got, err := command.Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"BuildCookRun",
+		"-project=Metaverse",
+		"-noP4",
+		"-unrealexe=UnrealEditor-Cmd.exe",
+		"-clientconfig=Development",
+		"-platform=Win64",
+		"-ini:Game:[/Script/UnrealEd.ProjectPackagingSettings]:BlueprintNativizationMethod=Disabled",
+		"-build",
+		"-cook",
+		"-unversionedcookedcontent",
+		"-SkipCookingEditorContent",
+		"-map=/Game/Maps/Lobby",
+		"-pak",
+		"-compressed",
+		"-package",
+		"-createreleaseversion=1.2.0",
+		"-stage",
+		"-stagingdirectory=C:/Metaverse Project/Saved/StagedBuilds",
+		"-VeryVerbose",
+		"-NoCodeSign",
+		"-BuildMachine",
+		"-AllowCommandletRendering",
+		"-utf8output",
+		"-nodebug",
+		"-nodebuginfo",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() =\n%q\nwant\n%q", got, want)
+	}
+
+	for _, arg := range got {
+		if strings.HasPrefix(arg, "-serveconfig") || strings.HasPrefix(arg, "-serverconfig") {
+			t.Errorf("unexpected server configuration %s", arg)
+		}
+	}
+}
+
+func TestNewPackageBuildCookRun(t *testing.T) {
+	withBuildGlobals(t, "D:/Unreal Projects/Metaverse/Metaverse.uproject", "D:/Unreal Engine/Engine/Binaries/Win64/UnrealEditor-Cmd.exe")
+
+	job := JobMetadata{
+		Platform:      "Win64",
+		Deployment:    "Server",
+		Configuration: "Development",
+		Package:       &Package{Name: "Forest", Map: "/Forest/Maps/Forest", Release: "1.2.0"},
+	}
+
+	command := newPackageBuildCookRun(job)
+	command.Distribution = true
+
+	got, err := command.Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"BuildCookRun",
+		"-project=D:/Unreal Projects/Metaverse/Metaverse.uproject",
+		"-noP4",
+		"-unrealexe=D:/Unreal Engine/Engine/Binaries/Win64/UnrealEditor-Cmd.exe",
+		"-noclient",
+		"-server",
+		"-serverconfig=Development",
+		"-serverplatform=Win64",
+		"-cook",
+		"-unversionedcookedcontent",
+		"-map=/Forest/Maps/Forest",
+		"-pak",
+		"-dlcname=Forest",
+		"-DLCIncludeEngineContent",
+		"-basedonreleaseversion=1.2.0",
+		"-compressed",
+		"-package",
+		"-skipstage",
+		"-distribution",
+		"-VeryVerbose",
+		"-BuildMachine",
+		"-utf8output",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestNewPackageBuildCookRunClient(t *testing.T) {
+	withBuildGlobals(t, "Metaverse", "UnrealEditor-Cmd")
+
+	job := JobMetadata{
+		Platform:      "Android",
+		Deployment:    "Client",
+		Configuration: "Shipping",
+		Package:       &Package{Name: "Forest", Map: "/Forest/Maps/Forest", Release: "1.2.0"},
+	}
+
+	got, err := newPackageBuildCookRun(job).Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"BuildCookRun",
+		"-project=Metaverse",
+		"-noP4",
+		"-unrealexe=UnrealEditor-Cmd",
+		"-clientconfig=Shipping",
+		"-platform=Android",
+		"-cook",
+		"-unversionedcookedcontent",
+		"-map=/Forest/Maps/Forest",
+		"-pak",
+		"-dlcname=Forest",
+		"-DLCIncludeEngineContent",
+		"-basedonreleaseversion=1.2.0",
+		"-compressed",
+		"-package",
+		"-skipstage",
+		"-VeryVerbose",
+		"-BuildMachine",
+		"-utf8output",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildPhase is a BuildCookRun phase timed by the command started and completed lines of UAT
+type BuildPhase struct {
+	Name     string    `json:"name"` // Lower case phase name, e.g. build, cook, stage, package or archive
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration"`           // Seconds
+	Complete bool      `json:"complete,omitempty"` // False if the run ended before the phase completed
+}
+
+// BuildTiming is a timing line reported by UAT or UBT, e.g. "Took 12.5s to run UnrealEditor-Cmd.exe"
+type BuildTiming struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration"` // Seconds
+}
+
+// BuildMetrics are the phase durations and counters of a single UAT run
+type BuildMetrics struct {
+	Phases          []*BuildPhase  `json:"phases,omitempty"`
+	Timings         []*BuildTiming `json:"timings,omitempty"`
+	PackagesCooked  int            `json:"packagesCooked,omitempty"`  // Number of cooked packages (assets and maps)
+	ShadersCompiled int            `json:"shadersCompiled,omitempty"` // Number of completed shader compile jobs
+}
+
+// Phase returns the phase with the name, nil if the run had no such phase
+func (m *BuildMetrics) Phase(name string) *BuildPhase {
+	for _, p := range m.Phases {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+var (
+	// BuildCookRun phase boundaries, e.g. "********** COOK COMMAND STARTED **********"
+	buildPhaseRegexp = regexp.MustCompile(`\*{5,}\s*(BUILD|COOK|STAGE|PACKAGE|ARCHIVE|DEPLOY|RUN)\s+COMMAND\s+(STARTED|COMPLETED)`)
+
+	// UAT and UBT timing lines
+	uatTookRegexp     = regexp.MustCompile(`Took ([\d.]+)\s*s to run ([^\s,]+)`)
+	uatCommandRegexp  = regexp.MustCompile(`^\s*(\w+) time: ([\d.]+)\s*s\b`)
+	ubtTotalRegexp    = regexp.MustCompile(`Total execution time: ([\d.]+) seconds`)
+	ubtParallelRegexp = regexp.MustCompile(`Total time in (.+?) executor: ([\d.]+) seconds`)
+
+	// Cook and shader counters, the last reported value wins
+	cookedPackagesRegexp  = regexp.MustCompile(`Cooked packages (\d+) Packages Remain \d+ Total \d+`)
+	compiledShadersRegexp = regexp.MustCompile(`Jobs assigned \d+, completed (\d+)`)
+)
+
+// buildMetricsParser collects the build metrics from the UAT output line by line, timing phases by the time lines are read
+type buildMetricsParser struct {
+	metrics BuildMetrics
+	current *BuildPhase
+}
+
+// ParseLine parses a single output line read at the time
+func (p *buildMetricsParser) ParseLine(line string, now time.Time) {
+	if m := buildPhaseRegexp.FindStringSubmatch(line); m != nil {
+		name := strings.ToLower(m[1])
+		if m[2] == "STARTED" {
+			p.end(now, false)
+			p.current = &BuildPhase{Name: name, Started: now}
+			p.metrics.Phases = append(p.metrics.Phases, p.current)
+		} else if p.current != nil && p.current.Name == name {
+			p.end(now, true)
+		}
+		return
+	}
+
+	if m := uatTookRegexp.FindStringSubmatch(line); m != nil {
+		p.addTiming(m[2], m[1])
+		return
+	}
+
+	if m := uatCommandRegexp.FindStringSubmatch(line); m != nil {
+		p.addTiming(m[1], m[2])
+		return
+	}
+
+	if m := ubtTotalRegexp.FindStringSubmatch(line); m != nil {
+		p.addTiming("UnrealBuildTool", m[1])
+		return
+	}
+
+	if m := ubtParallelRegexp.FindStringSubmatch(line); m != nil {
+		p.addTiming(m[1]+" executor", m[2])
+		return
+	}
+
+	if m := cookedPackagesRegexp.FindStringSubmatch(line); m != nil {
+		p.metrics.PackagesCooked = atoi(m[1])
+		return
+	}
+
+	if m := compiledShadersRegexp.FindStringSubmatch(line); m != nil {
+		p.metrics.ShadersCompiled = atoi(m[1])
+		return
+	}
+}
+
+// Metrics ends the running phase and returns the collected metrics
+func (p *buildMetricsParser) Metrics(now time.Time) *BuildMetrics {
+	p.end(now, false)
+	return &p.metrics
+}
+
+// end ends the running phase
+func (p *buildMetricsParser) end(now time.Time, complete bool) {
+	if p.current == nil {
+		return
+	}
+
+	p.current.Duration = now.Sub(p.current.Started).Seconds()
+	p.current.Complete = complete
+	p.current = nil
+}
+
+// addTiming adds the timing line, invalid durations are skipped
+func (p *buildMetricsParser) addTiming(name string, seconds string) {
+	d, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return
+	}
+
+	p.metrics.Timings = append(p.metrics.Timings, &BuildTiming{Name: name, Duration: d})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildProfile adds or removes UAT arguments, ini overrides and environment variables for the matching BuildCookRun jobs.
+// Automatic profiles apply to every matching job, other profiles apply only when requested by name in the job metadata.
+type BuildProfile struct {
+	Name           string            `json:"name"`
+	Auto           bool              `json:"auto,omitempty"`           // Apply to all matching jobs without being requested
+	JobTypes       []string          `json:"jobTypes,omitempty"`       // Job types to match, any if empty
+	Deployments    []string          `json:"deployments,omitempty"`    // Job deployments to match, any if empty
+	Platforms      []string          `json:"platforms,omitempty"`      // Job platforms to match, any if empty
+	Configurations []string          `json:"configurations,omitempty"` // Job configurations to match, any if empty
+	AddArgs        []string          `json:"addArgs,omitempty"`        // Arguments appended to the command
+	RemoveArgs     []string          `json:"removeArgs,omitempty"`     // Arguments removed from the command, "-map" also removes "-map=..."
+	Ini            []IniOverride     `json:"ini,omitempty"`            // Config overrides appended to the command
+	Env            map[string]string `json:"env,omitempty"`            // Environment variables set for the UAT process
+}
+
+// defaultBuildProfiles reproduce the staging options of release builds, config profiles with the same name replace them
+var defaultBuildProfiles = []BuildProfile{
+	{
+		Name:           "shipping",
+		Auto:           true,
+		JobTypes:       []string{"Release"},
+		Deployments:    []string{"Client", "Server"},
+		Configurations: []string{"Shipping"},
+		AddArgs:        []string{"-CrashReporter", "-nodebug", "-nodebuginfo", "-distribution", "-prereqs"},
+	},
+	{
+		Name:           "debug",
+		Auto:           true,
+		JobTypes:       []string{"Release"},
+		Deployments:    []string{"Client", "Server"},
+		Configurations: []string{"Development", "DebugGame", "Debug", "Test"},
+		AddArgs:        []string{"-debug"},
+	},
+}
+
+// buildProfiles returns the default profiles overridden and extended by the configured ones
+func buildProfiles() []BuildProfile {
+	var profiles []BuildProfile
+	for _, p := range defaultBuildProfiles {
+		overridden := false
+		for _, c := range config.BuildProfiles {
+			if c.Name == p.Name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			profiles = append(profiles, p)
+		}
+	}
+
+	return append(profiles, config.BuildProfiles...)
+}
+
+// matches reports whether the profile selectors match the job
+func (p BuildProfile) matches(job JobMetadata) bool {
+	return matchesAny(p.JobTypes, job.Type) &&
+		matchesAny(p.Deployments, job.Deployment) &&
+		matchesAny(p.Platforms, job.Platform) &&
+		matchesAny(p.Configurations, job.Configuration)
+}
+
+// matchesAny reports whether the value is in the list, an empty list matches any value
+func matchesAny(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectBuildProfiles returns the automatic profiles matching the job followed by the profiles requested by the job metadata
+func selectBuildProfiles(job JobMetadata) ([]BuildProfile, error) {
+	profiles := buildProfiles()
+
+	var selected []BuildProfile
+	for _, p := range profiles {
+		if p.Auto && p.matches(job) {
+			selected = append(selected, p)
+		}
+	}
+
+	for _, name := range job.BuildProfiles {
+		found := false
+		for _, p := range profiles {
+			if p.Name != name {
+				continue
+			}
+			found = true
+			if p.Auto {
+				// Already applied if matching
+				break
+			}
+			if !p.matches(job) {
+				return nil, fmt.Errorf("build profile %s does not apply to %s %s %s %s jobs", name, job.Type, job.Deployment, job.Platform, job.Configuration)
+			}
+			selected = append(selected, p)
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown build profile: %s", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// applyJobBuildProfiles applies the profiles selected for the job to the rendered UAT arguments and returns the environment to set
+func applyJobBuildProfiles(job JobMetadata, args []string) ([]string, map[string]string, error) {
+	profiles, err := selectBuildProfiles(job)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env := map[string]string{}
+	for _, p := range profiles {
+		Logger.Infof("applying build profile %s", p.Name)
+
+		args = removeArgs(args, p.RemoveArgs)
+
+		for _, o := range p.Ini {
+			args = addArg(args, o.String())
+		}
+
+		for _, a := range p.AddArgs {
+			args = addArg(args, a)
+		}
+
+		for k, v := range p.Env {
+			env[k] = v
+		}
+	}
+
+	return args, env, nil
+}
+
+// removeArgs removes the arguments equal to any of the listed ones or assigning a value to them, case-insensitive
+func removeArgs(args []string, remove []string) []string {
+	var result []string
+	for _, a := range args {
+		removed := false
+		for _, r := range remove {
+			if strings.EqualFold(a, r) || (len(a) > len(r) && strings.EqualFold(a[:len(r)+1], r+"=")) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			result = append(result, a)
+		}
+	}
+
+	return result
+}
+
+// addArg appends the argument unless already present
+func addArg(args []string, arg string) []string {
+	for _, a := range args {
+		if strings.EqualFold(a, arg) {
+			return args
+		}
+	}
+
+	return append(args, arg)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// configFileName is the name of the optional configuration file looked up next to the tool binary
+const configFileName = ".veverse-automation.json"
+
+// Config is the optional tool configuration for settings which do not fit environment variables
+type Config struct {
+	BuildProfiles []BuildProfile      `json:"buildProfiles,omitempty"` // Additional UAT arguments, ini overrides and environment per job
+	Timeouts      TimeoutConfig       `json:"timeouts,omitempty"`      // Timeouts of external tool runs
+	LogStream     LogStreamConfig     `json:"logStream,omitempty"`     // Live streaming of the tool output to the API
+	LogArchive    LogArchiveConfig    `json:"logArchive,omitempty"`    // Archiving of the full job logs
+	MetricsPath   string              `json:"metricsPath,omitempty"`   // Local JSON lines file collecting the build metrics of all jobs, default job-metrics.jsonl
+	Retry         RetryConfig         `json:"retry,omitempty"`         // Automatic retry of transient AutomationTool failures
+	SmokeTest     SmokeTestConfig     `json:"smokeTest,omitempty"`     // Verification of staged server releases
+	QualityGate   QualityGateConfig   `json:"qualityGate,omitempty"`   // Warning budget of release jobs
+	Engines       []EngineConfig      `json:"engines,omitempty"`       // Engine installations selectable per job, the project engine association is resolved against them
+	PackagePolicy PackagePolicyConfig `json:"packagePolicy,omitempty"` // Validation of the user packages before cooking
+}
+
+// PackagePolicyConfig configures what the user packages may contain
+type PackagePolicyConfig struct {
+	AllowedPlugins []string          `json:"allowedPlugins,omitempty"` // Plugins the package descriptors may depend on, the plugins enabled by the project if empty
+	AllowedFiles   []ContentFileRule `json:"allowedFiles,omitempty"`   // File types allowed in the package content, replaces the default asset types if set
+	MaxTotalSize   *ByteSize         `json:"maxTotalSize,omitempty"`   // Maximum unpacked size of the package content, default 4GiB, "0" for unlimited
+}
+
+// ContentFileRule allows a file type in the package content
+type ContentFileRule struct {
+	Extension string   `json:"extension"`         // File extension including the dot, e.g. ".uasset"
+	MaxSize   ByteSize `json:"maxSize,omitempty"` // Maximum size of a single file, unlimited if zero
+}
+
+// LogArchiveConfig configures the per-job log directories uploaded as the job log archive
+type LogArchiveConfig struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Dir      string `json:"dir,omitempty"`  // Directory keeping the per-job log directories, default job-logs in the working directory
+	Keep     *int   `json:"keep,omitempty"` // Number of the newest job log directories kept locally including the current one, default 20
+}
+
+// LogStreamConfig configures the live streaming of the tool output to the API
+type LogStreamConfig struct {
+	Disabled      bool     `json:"disabled,omitempty"`
+	BatchSize     int      `json:"batchSize,omitempty"`     // Maximum number of lines per request, default 500
+	BufferSize    int      `json:"bufferSize,omitempty"`    // Maximum number of buffered lines, new lines are dropped when full, default 20000
+	FlushInterval Duration `json:"flushInterval,omitempty"` // Maximum time lines wait before being sent, default 2s
+}
+
+// TimeoutConfig configures when a stuck external tool run is killed
+type TimeoutConfig struct {
+	JobTypes   map[string]Duration `json:"jobTypes,omitempty"`   // Wall-clock timeout of a single tool run per job type, e.g. {"Release": "12h"}
+	Inactivity *Duration           `json:"inactivity,omitempty"` // Maximum time without output, "0s" disables the watchdog
+}
+
+// Duration is a time.Duration read from strings such as "90m" or "12h"
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s, expected a string such as \"90m\"", string(b))
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: %v", s, err)
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON formats the duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ByteSize is a size in bytes read from strings such as "512MiB" or from numbers
+type ByteSize int64
+
+// UnmarshalJSON parses the size string or number
+func (s *ByteSize) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err == nil {
+		*s = ByteSize(n)
+		return nil
+	}
+
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("invalid size %s, expected a string such as \"512MiB\"", string(b))
+	}
+
+	n, err := parseByteSize(v)
+	if err != nil {
+		return err
+	}
+
+	*s = ByteSize(n)
+
+	return nil
+}
+
+// defaultJobTimeouts are the wall-clock timeouts of a single tool run per job type used unless configured
+var defaultJobTimeouts = map[string]time.Duration{
+	"Release":  12 * time.Hour,
+	"Package":  3 * time.Hour,
+	"Launcher": time.Hour,
+	"Test":     3 * time.Hour,
+}
+
+// defaultInactivityTimeout is the maximum time without output used unless configured
+const defaultInactivityTimeout = time.Hour
+
+// jobTimeouts returns the wall-clock and inactivity timeouts for a tool run of the job
+func jobTimeouts(job JobMetadata) (timeout time.Duration, inactivity time.Duration) {
+	timeout = defaultJobTimeouts[job.Type]
+	if d, ok := config.Timeouts.JobTypes[job.Type]; ok {
+		timeout = time.Duration(d)
+	}
+
+	inactivity = defaultInactivityTimeout
+	if config.Timeouts.Inactivity != nil {
+		inactivity = time.Duration(*config.Timeouts.Inactivity)
+	}
+
+	return
+}
+
+// config is the loaded tool configuration, zero value if there is no configuration file
+var config Config
+
+// loadConfig loads the configuration file from VAT_CONFIG_PATH or from the tool binary directory if present
+func loadConfig() error {
+	path := os.Getenv("VAT_CONFIG_PATH")
+	if path == "" {
+		ex, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to get the executable path: %v", err)
+		}
+
+		path = filepath.Join(filepath.Dir(ex), configFileName)
+		if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			Logger.Infof("no configuration file found at %s, using defaults", path)
+			return nil
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration file: %v", err)
+	}
+
+	var c Config
+	if err = json.Unmarshal(b, &c); err != nil {
+		return fmt.Errorf("failed to parse the configuration file %s: %v", path, err)
+	}
+
+	config = c
+
+	if _, err = retryRules(); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+
+	if a := c.QualityGate.Action; a != "" && a != warningBudgetActionFail && a != warningBudgetActionFlag {
+		return fmt.Errorf("invalid configuration file %s: unsupported quality gate action %s", path, a)
+	}
+
+	if err = loadEngineRegistry(config.Engines); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+
+	for _, rule := range c.PackagePolicy.AllowedFiles {
+		if !strings.HasPrefix(rule.Extension, ".") {
+			return fmt.Errorf("invalid configuration file %s: allowed package file extension %q must start with a dot", path, rule.Extension)
+		}
+		if kind, ok := contentDeniedExtensions[strings.ToLower(rule.Extension)]; ok {
+			return fmt.Errorf("invalid configuration file %s: %s files (%s) can not be allowed in packages", path, rule.Extension, kind)
+		}
+	}
+
+	Logger.Infof("loaded configuration from %s", path)
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	contentPolicyListLimit  = 50      // Maximum number of violations listed in the failure message
+	contentPolicyHeaderSize = 4 << 20 // Number of the first bytes of each asset searched for the disallowed classes
+)
+
+// defaultContentFileRules are the file types allowed in the package content unless configured
+var defaultContentFileRules = []ContentFileRule{
+	{Extension: ".uasset", MaxSize: 1 << 30},
+	{Extension: ".umap", MaxSize: 1 << 30},
+	{Extension: ".uexp", MaxSize: 2 << 30},
+	{Extension: ".ubulk", MaxSize: 2 << 30},
+	{Extension: ".uptnl", MaxSize: 2 << 30},
+}
+
+// defaultContentMaxTotalSize is the maximum unpacked size of the package content unless configured
+const defaultContentMaxTotalSize = 4 << 30
+
+// contentDeniedDirs are the directories which can not be in the package content even if their files are allowed
+var contentDeniedDirs = map[string]string{
+	"source":       "source code",
+	"binaries":     "compiled binaries",
+	"intermediate": "build intermediates",
+	"config":       "configuration overrides",
+}
+
+// contentDeniedExtensions are the file types which can not be allowed by the configuration
+var contentDeniedExtensions = map[string]string{
+	".py": "Python script", ".pyc": "Python script",
+	".cpp": "source code", ".c": "source code", ".cc": "source code", ".h": "source code", ".hpp": "source code", ".inl": "source code", ".cs": "source code",
+	".dll": "binary", ".so": "binary", ".dylib": "binary", ".exe": "binary", ".lib": "binary", ".a": "binary", ".pdb": "binary", ".modules": "binary", ".target": "binary",
+	".bat": "shell script", ".cmd": "shell script", ".sh": "shell script", ".ps1": "shell script", ".command": "shell script",
+}
+
+// contentDeniedClasses are the script packages referenced by the assets running code in the editor, found in the asset name table
+var contentDeniedClasses = []struct {
+	marker string
+	kind   string
+}{
+	{"/Script/Blutility", "Blueprint editor utility"},
+	{"/Script/PythonScriptPlugin", "Python script call"},
+}
+
+const (
+	ContentRulePath          = "path"
+	ContentRuleSymlink       = "symlink"
+	ContentRuleDirectory     = "directory"
+	ContentRuleDenied        = "denied"
+	ContentRuleExtension     = "extension"
+	ContentRuleSize          = "size"
+	ContentRuleTotalSize     = "total-size"
+	ContentRuleEditorUtility = "editor-utility"
+)
+
+// ContentPolicyViolation is a file of the package content rejected by the content policy
+type ContentPolicyViolation struct {
+	Path   string `json:"path"`
+	Rule   string `json:"rule"`   // path, symlink, directory, denied, extension, size, total-size or editor-utility
+	Reason string `json:"reason"` // Explanation for the package creator
+	Size   int64  `json:"size,omitempty"`
+}
+
+// ContentPolicyReport is the result of the package content scan reported with the job log
+type ContentPolicyReport struct {
+	Files      int                       `json:"files"`
+	TotalSize  int64                     `json:"totalSize"`
+	Violations []*ContentPolicyViolation `json:"violations,omitempty"`
+	Passed     bool                      `json:"passed"`
+}
+
+// checkPackageContent scans the package content archive before it is unpacked and reports the result with the job log,
+// returns an error listing the violations if the content is rejected
+func checkPackageContent(job JobMetadata, zipPath string) error {
+	report, err := scanPackageContent(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to scan the package content: %v", err)
+	}
+
+	if r := currentJobReport(job); r != nil {
+		r.setContentPolicy(report)
+	}
+
+	Logger.Infof("scanned %d package content files of %s, %d violations", report.Files, formatByteSize(report.TotalSize), len(report.Violations))
+
+	if report.Passed {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("package content rejected, %d files violate the content policy:", len(report.Violations)))
+	for i, v := range report.Violations {
+		if i == contentPolicyListLimit {
+			b.WriteString(fmt.Sprintf("\n... and %d more", len(report.Violations)-i))
+			break
+		}
+		b.WriteString(fmt.Sprintf("\n%s: %s", v.Path, v.Reason))
+	}
+
+	return fmt.Errorf("%s", b.String())
+}
+
+// scanPackageContent checks the paths, types and sizes of the archived files and searches the assets for the editor utility classes
+func scanPackageContent(zipPath string) (report *ContentPolicyReport, err error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", zipPath, err)
+	}
+
+	defer func() {
+		if err1 := r.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", zipPath, err1)
+		}
+	}()
+
+	rules := map[string]ContentFileRule{}
+	allowed := config.PackagePolicy.AllowedFiles
+	if len(allowed) == 0 {
+		allowed = defaultContentFileRules
+	}
+	for _, rule := range allowed {
+		rules[strings.ToLower(rule.Extension)] = rule
+	}
+
+	maxTotalSize := int64(defaultContentMaxTotalSize)
+	if config.PackagePolicy.MaxTotalSize != nil {
+		maxTotalSize = int64(*config.PackagePolicy.MaxTotalSize)
+	}
+
+	report = &ContentPolicyReport{}
+	violation := func(f *zip.File, rule string, reason string) {
+		report.Violations = append(report.Violations, &ContentPolicyViolation{Path: f.Name, Rule: rule, Reason: reason, Size: int64(f.UncompressedSize64)})
+	}
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		report.Files++
+		report.TotalSize += int64(f.UncompressedSize64)
+
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		if path.IsAbs(name) || strings.Contains(name, ":") || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+			violation(f, ContentRulePath, "path outside the package content")
+			continue
+		}
+
+		if f.Mode()&os.ModeSymlink != 0 {
+			violation(f, ContentRuleSymlink, "symbolic links are not allowed")
+			continue
+		}
+
+		if dir := contentDeniedDir(name); dir != "" {
+			violation(f, ContentRuleDirectory, fmt.Sprintf("%s directory is not allowed (%s)", dir, contentDeniedDirs[strings.ToLower(dir)]))
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		if kind, ok := contentDeniedExtensions[ext]; ok {
+			violation(f, ContentRuleDenied, fmt.Sprintf("%s files are not allowed (%s)", ext, kind))
+			continue
+		}
+
+		rule, ok := rules[ext]
+		if !ok {
+			violation(f, ContentRuleExtension, fmt.Sprintf("file type %s is not allowed, allowed types: %s", contentExtensionName(ext), contentAllowedExtensions(allowed)))
+			continue
+		}
+
+		if rule.MaxSize > 0 && int64(f.UncompressedSize64) > int64(rule.MaxSize) {
+			violation(f, ContentRuleSize, fmt.Sprintf("file size %s exceeds the %s limit of %s files", formatByteSize(int64(f.UncompressedSize64)), formatByteSize(int64(rule.MaxSize)), ext))
+			continue
+		}
+
+		if ext == ".uasset" || ext == ".umap" {
+			kind, err := findDeniedAssetClass(f)
+			if err != nil {
+				return nil, err
+			}
+			if kind != "" {
+				violation(f, ContentRuleEditorUtility, fmt.Sprintf("%s assets are not allowed", kind))
+			}
+		}
+	}
+
+	if maxTotalSize > 0 && report.TotalSize > maxTotalSize {
+		report.Violations = append(report.Violations, &ContentPolicyViolation{
+			Path:   "*",
+			Rule:   ContentRuleTotalSize,
+			Reason: fmt.Sprintf("total content size %s exceeds the limit of %s", formatByteSize(report.TotalSize), formatByteSize(maxTotalSize)),
+			Size:   report.TotalSize,
+		})
+	}
+
+	report.Passed = len(report.Violations) == 0
+
+	return report, nil
+}
+
+// contentDeniedDir returns the first denied directory of the path, empty if there is none
+func contentDeniedDir(name string) string {
+	parts := strings.Split(name, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if _, ok := contentDeniedDirs[strings.ToLower(dir)]; ok {
+			return dir
+		}
+	}
+
+	return ""
+}
+
+// findDeniedAssetClass searches the asset header for the script packages of the denied classes, returns the kind of the first one found
+func findDeniedAssetClass(f *zip.File) (kind string, err error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %v", f.Name, err)
+	}
+
+	defer func() {
+		if err1 := rc.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", f.Name, err1)
+		}
+	}()
+
+	header, err := io.ReadAll(io.LimitReader(rc, contentPolicyHeaderSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", f.Name, err)
+	}
+
+	for _, c := range contentDeniedClasses {
+		if bytes.Contains(header, []byte(c.marker)) {
+			return c.kind, nil
+		}
+	}
+
+	return "", nil
+}
+
+// contentExtensionName returns the extension for the messages, files without an extension are named as such
+func contentExtensionName(ext string) string {
+	if ext == "" {
+		return "without extension"
+	}
+
+	return ext
+}
+
+// contentAllowedExtensions returns the allowed extensions for the messages
+func contentAllowedExtensions(rules []ContentFileRule) string {
+	var extensions []string
+	for _, rule := range rules {
+		extensions = append(extensions, rule.Extension)
+	}
+
+	return strings.Join(extensions, ", ")
+}
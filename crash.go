@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// crashSummaryFrames is the number of callstack frames included into the job error message
+const crashSummaryFrames = 5
+
+// CrashReport is a crash or a failed assertion of the editor or a tool run by UAT
+type CrashReport struct {
+	Kind      string   `json:"kind"`    // Assert for failed assertions and fatal errors, Crash for unhandled exceptions and signals
+	Message   string   `json:"message"` // e.g. "Assertion failed: IsValid()" or "Unhandled Exception: SIGSEGV"
+	Callstack []string `json:"callstack,omitempty"`
+	Guid      string   `json:"guid,omitempty"`     // Crash GUID from the crash context
+	Dir       string   `json:"dir,omitempty"`      // Crash folder with the minidump and the crash context
+	Artifact  string   `json:"artifact,omitempty"` // Name of the uploaded crash folder archive
+}
+
+// String formats the crash message with the top callstack frames
+func (c *CrashReport) String() string {
+	s := c.Message
+	for i, frame := range c.Callstack {
+		if i == crashSummaryFrames {
+			s += fmt.Sprintf("\n  ... %d more frames", len(c.Callstack)-i)
+			break
+		}
+		s += "\n  " + frame
+	}
+
+	return s
+}
+
+// crashSummary formats the first crash for the job error message, empty if there are no crashes
+func crashSummary(crashes []*CrashReport) string {
+	if len(crashes) == 0 {
+		return ""
+	}
+
+	s := ", crashed: " + crashes[0].String()
+	if len(crashes) > 1 {
+		s += fmt.Sprintf("\n%d more crashes in the job report", len(crashes)-1)
+	}
+
+	return s
+}
+
+// unrealCrashContext is the part of the CrashContext.runtime-xml written by the engine crash handler
+type unrealCrashContext struct {
+	RuntimeProperties struct {
+		CrashGUID    string `xml:"CrashGUID"`
+		ErrorMessage string `xml:"ErrorMessage"`
+		CallStack    string `xml:"CallStack"`
+	} `xml:"RuntimeProperties"`
+}
+
+// jobCrashDirs returns the Saved/Crashes directories of the project, the package plugin and the engine written by the job tools
+func jobCrashDirs(job JobMetadata) []string {
+	var dirs []string
+
+	if projectDir != "" {
+		dirs = append(dirs, filepath.Join(projectDir, "Saved", "Crashes"))
+		if job.Type == "Package" && job.Package != nil && job.Package.Name != "" {
+			dirs = append(dirs, filepath.Join(projectDir, "Plugins", job.Package.Name, "Saved", "Crashes"))
+		}
+	}
+
+	if engineDir := jobEngineDir(); engineDir != "" {
+		dirs = append(dirs, filepath.Join(engineDir, "Saved", "Crashes"))
+	}
+
+	return dirs
+}
+
+// collectUnrealCrashes returns the crashes found in the log entries, the newest crash folder created since the time completes the first crash
+// or is reported on its own if the log has no crash signature
+func collectUnrealCrashes(entries []*LogEntry, crashDirs []string, since time.Time) []*CrashReport {
+	var crashes []*CrashReport
+	for _, e := range entries {
+		if e.Severity != LogSeverityError || (e.Kind != LogKindAssert && e.Kind != LogKindCrash) {
+			continue
+		}
+		crashes = append(crashes, &CrashReport{Kind: e.Kind, Message: e.Message, Callstack: e.Callstack})
+	}
+
+	dir := newestCrashDir(crashDirs, since)
+	if dir == "" {
+		return crashes
+	}
+
+	crash := &CrashReport{Kind: LogKindCrash, Message: "Crash"}
+	if len(crashes) > 0 {
+		crash = crashes[0]
+	} else {
+		crashes = append(crashes, crash)
+	}
+	crash.Dir = dir
+
+	context, err := readUnrealCrashContext(dir)
+	if err != nil {
+		Logger.Warningf("failed to read crash context: %v", err)
+		return crashes
+	}
+
+	crash.Guid = strings.TrimSpace(context.RuntimeProperties.CrashGUID)
+	if msg := strings.TrimSpace(context.RuntimeProperties.ErrorMessage); msg != "" && crash.Message == "Crash" {
+		crash.Message = msg
+	}
+	if len(crash.Callstack) == 0 {
+		for _, line := range strings.Split(context.RuntimeProperties.CallStack, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				crash.Callstack = append(crash.Callstack, line)
+			}
+		}
+	}
+
+	return crashes
+}
+
+// newestCrashDir returns the newest crash folder modified since the time, empty if none
+func newestCrashDir(crashDirs []string, since time.Time) (newest string) {
+	var newestTime time.Time
+	for _, root := range crashDirs {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+
+			info, err := e.Info()
+			if err != nil || info.ModTime().Before(since) {
+				continue
+			}
+
+			if info.ModTime().After(newestTime) {
+				newest = filepath.Join(root, e.Name())
+				newestTime = info.ModTime()
+			}
+		}
+	}
+
+	return
+}
+
+// readUnrealCrashContext parses the crash context of the crash folder
+func readUnrealCrashContext(dir string) (*unrealCrashContext, error) {
+	path := filepath.Join(dir, "CrashContext.runtime-xml")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var context unrealCrashContext
+	if err = xml.Unmarshal(b, &context); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return &context, nil
+}
+
+// uploadCrashArtifacts compresses the crash folders with the minidumps and the crash contexts and uploads them to the API
+func uploadCrashArtifacts(job JobMetadata, crashes []*CrashReport) {
+	for _, crash := range crashes {
+		if crash.Dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(crash.Dir)
+		if err != nil {
+			Logger.Errorf("failed to read crash folder %s: %v", crash.Dir, err)
+			continue
+		}
+
+		files := map[string]string{}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files[filepath.Join(crash.Dir, e.Name())] = e.Name()
+			}
+		}
+
+		name := filepath.Base(crash.Dir) + ".zip"
+		zipPath := filepath.Join(os.TempDir(), name)
+		if err = zipFiles(zipPath, files); err != nil {
+			Logger.Errorf("failed to compress crash folder %s: %v", crash.Dir, err)
+			continue
+		}
+
+		if err = uploadCrashArtifactFile(job, zipPath, name, nil); err != nil {
+			Logger.Errorf("failed to upload crash folder %s: %v", crash.Dir, err)
+		} else {
+			crash.Artifact = name
+		}
+
+		if err = os.Remove(zipPath); err != nil {
+			Logger.Warningf("failed to remove %s: %v", zipPath, err)
+		}
+	}
+}
+
+// uploadCrashArtifactFile uploads the compressed crash folder to the API for storage
+func uploadCrashArtifactFile(job JobMetadata, path string, originalPath string, params map[string]string) error {
+	Logger.Infof("uploading crash artifact %s", path)
+
+	var (
+		fileType = "job-crash"
+		fileMime = "application/zip"
+	)
+
+	return uploadJobEntityFile(job, job.Id, fileType, fileMime, path, originalPath, params)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// crashContextFixture is a CrashContext.runtime-xml written by the engine crash handler, shortened to the parsed properties
+const crashContextFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<FGenericCrashContext>
+	<RuntimeProperties>
+		<CrashVersion>3</CrashVersion>
+		<ExecutionGuid>2C1A7E5B4F3D4E2A9B8C7D6E5F4A3B2C</ExecutionGuid>
+		<CrashGUID>UECC-Windows-4F3C2B1A0E9D8C7B6A5F4E3D2C1B0A9F_0000</CrashGUID>
+		<IsEnsure>false</IsEnsure>
+		<IsAssert>false</IsAssert>
+		<CrashType>Crash</CrashType>
+		<ErrorMessage>Unhandled Exception: EXCEPTION_ACCESS_VIOLATION reading address 0x0000000000000000</ErrorMessage>
+		<CallStack>UnrealEditor_Metaverse!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]
+UnrealEditor_Engine!AActor::TickActor() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:1407]
+
+UnrealEditor_Engine!FActorTickFunction::ExecuteTick() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:274]
+</CallStack>
+		<GameName>UE-Metaverse</GameName>
+	</RuntimeProperties>
+</FGenericCrashContext>
+`
+
+// writeCrashDir creates a crash folder with the crash context and the modification time
+func writeCrashDir(t *testing.T, root string, name string, context string, modTime time.Time) string {
+	t.Helper()
+
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create crash folder: %v", err)
+	}
+
+	if context != "" {
+		if err := os.WriteFile(filepath.Join(dir, "CrashContext.runtime-xml"), []byte(context), 0644); err != nil {
+			t.Fatalf("failed to write crash context: %v", err)
+		}
+	}
+
+	if err := os.Chtimes(dir, modTime, modTime); err != nil {
+		t.Fatalf("failed to set crash folder time: %v", err)
+	}
+
+	return dir
+}
+
+func TestNewestCrashDir(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+
+	projectCrashes := t.TempDir()
+	engineCrashes := t.TempDir()
+
+	writeCrashDir(t, projectCrashes, "UECC-Windows-Old", crashContextFixture, since.Add(-time.Minute))
+	writeCrashDir(t, projectCrashes, "UECC-Windows-First", crashContextFixture, since.Add(time.Minute))
+	newest := writeCrashDir(t, engineCrashes, "UECC-Windows-Second", crashContextFixture, since.Add(2*time.Minute))
+	if err := os.WriteFile(filepath.Join(projectCrashes, "CrashReportClient.log"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		dirs  []string
+		since time.Time
+		want  string
+	}{
+		{"newest of all roots", []string{projectCrashes, engineCrashes, filepath.Join(projectCrashes, "Missing")}, since, newest},
+		{"created since", []string{projectCrashes}, since, filepath.Join(projectCrashes, "UECC-Windows-First")},
+		{"none since", []string{projectCrashes, engineCrashes}, since.Add(time.Hour), ""},
+		{"missing roots", []string{filepath.Join(projectCrashes, "Missing")}, since, ""},
+	}
+
+	for _, tt := range tests {
+		if got := newestCrashDir(tt.dirs, tt.since); got != tt.want {
+			t.Errorf("%s: newestCrashDir() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectUnrealCrashes(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+
+	crashes := t.TempDir()
+	dir := writeCrashDir(t, crashes, "UECC-Windows-4F3C2B1A", crashContextFixture, since.Add(time.Minute))
+
+	noContext := t.TempDir()
+	noContextDir := writeCrashDir(t, noContext, "UECC-Windows-NoContext", "", since.Add(time.Minute))
+
+	contextCallstack := []string{
+		`UnrealEditor_Metaverse!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+		`UnrealEditor_Engine!AActor::TickActor() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:1407]`,
+		`UnrealEditor_Engine!FActorTickFunction::ExecuteTick() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:274]`,
+	}
+
+	assert := &LogEntry{Severity: LogSeverityError, Kind: LogKindAssert, Category: "Windows", Message: "Assertion failed: IsValid(World)", Callstack: []string{"UnrealEditor-Metaverse.dll!AVeGameMode::InitGame()"}, Count: 1}
+	ensure := &LogEntry{Severity: LogSeverityError, Kind: LogKindEnsure, Category: "OutputDevice", Message: "Ensure condition failed: Component != nullptr", Count: 1}
+	warning := &LogEntry{Severity: LogSeverityWarning, Kind: LogKindCrash, Message: "Caught signal 11 Segmentation fault", Count: 1}
+
+	tests := []struct {
+		name    string
+		entries []*LogEntry
+		dirs    []string
+		want    []*CrashReport
+	}{
+		{
+			name:    "crash folder only",
+			entries: []*LogEntry{ensure, warning},
+			dirs:    []string{crashes},
+			want: []*CrashReport{
+				{Kind: LogKindCrash, Message: "Unhandled Exception: EXCEPTION_ACCESS_VIOLATION reading address 0x0000000000000000", Callstack: contextCallstack, Guid: "UECC-Windows-4F3C2B1A0E9D8C7B6A5F4E3D2C1B0A9F_0000", Dir: dir},
+			},
+		},
+		{
+			name:    "log crash completed by the crash folder",
+			entries: []*LogEntry{assert},
+			dirs:    []string{crashes},
+			want: []*CrashReport{
+				{Kind: LogKindAssert, Message: "Assertion failed: IsValid(World)", Callstack: []string{"UnrealEditor-Metaverse.dll!AVeGameMode::InitGame()"}, Guid: "UECC-Windows-4F3C2B1A0E9D8C7B6A5F4E3D2C1B0A9F_0000", Dir: dir},
+			},
+		},
+		{
+			name:    "no crash folder",
+			entries: []*LogEntry{assert},
+			dirs:    []string{filepath.Join(crashes, "Missing")},
+			want: []*CrashReport{
+				{Kind: LogKindAssert, Message: "Assertion failed: IsValid(World)", Callstack: []string{"UnrealEditor-Metaverse.dll!AVeGameMode::InitGame()"}},
+			},
+		},
+		{
+			name: "crash folder without the crash context",
+			dirs: []string{noContext},
+			want: []*CrashReport{
+				{Kind: LogKindCrash, Message: "Crash", Dir: noContextDir},
+			},
+		},
+		{
+			name: "no crashes",
+			dirs: []string{filepath.Join(crashes, "Missing")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collectUnrealCrashes(tt.entries, tt.dirs, since)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectUnrealCrashes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
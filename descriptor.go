@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ModuleDescriptor is a code module of a project or a plugin
+type ModuleDescriptor struct {
+	Name              string   `json:"Name"`
+	Type              string   `json:"Type"`                        // Runtime, Editor, Developer, etc.
+	LoadingPhase      string   `json:"LoadingPhase,omitempty"`      // Default, PostEngineInit, etc.
+	PlatformAllowList []string `json:"PlatformAllowList,omitempty"` // Platforms the module is compiled for
+	PlatformDenyList  []string `json:"PlatformDenyList,omitempty"`  // Platforms the module is not compiled for
+}
+
+// PluginReferenceDescriptor is a plugin enabled or disabled by a project or required by a plugin
+type PluginReferenceDescriptor struct {
+	Name              string   `json:"Name"`
+	Enabled           bool     `json:"Enabled"`
+	Optional          bool     `json:"Optional,omitempty"`
+	MarketplaceURL    string   `json:"MarketplaceURL,omitempty"`
+	PlatformAllowList []string `json:"PlatformAllowList,omitempty"`
+	PlatformDenyList  []string `json:"PlatformDenyList,omitempty"`
+}
+
+// ProjectDescriptor is the project .uproject file
+type ProjectDescriptor struct {
+	FileVersion       int                         `json:"FileVersion"`
+	EngineAssociation string                      `json:"EngineAssociation"` // Engine version or {GUID} of a source build
+	Category          string                      `json:"Category,omitempty"`
+	Description       string                      `json:"Description,omitempty"`
+	Modules           []ModuleDescriptor          `json:"Modules,omitempty"`
+	Plugins           []PluginReferenceDescriptor `json:"Plugins,omitempty"`
+	TargetPlatforms   []string                    `json:"TargetPlatforms,omitempty"`
+}
+
+// PluginDescriptor is the plugin .uplugin file
+type PluginDescriptor struct {
+	FileVersion              int                         `json:"FileVersion"`
+	Version                  int                         `json:"Version"`
+	VersionName              string                      `json:"VersionName,omitempty"`
+	FriendlyName             string                      `json:"FriendlyName,omitempty"`
+	Description              string                      `json:"Description,omitempty"`
+	Category                 string                      `json:"Category,omitempty"`
+	CreatedBy                string                      `json:"CreatedBy,omitempty"`
+	CreatedByURL             string                      `json:"CreatedByURL,omitempty"`
+	DocsURL                  string                      `json:"DocsURL,omitempty"`
+	MarketplaceURL           string                      `json:"MarketplaceURL,omitempty"`
+	SupportURL               string                      `json:"SupportURL,omitempty"`
+	EngineVersion            string                      `json:"EngineVersion,omitempty"` // Engine version the plugin was created with, e.g. 5.1.0
+	CanContainContent        bool                        `json:"CanContainContent"`
+	IsBetaVersion            bool                        `json:"IsBetaVersion,omitempty"`
+	IsExperimentalVersion    bool                        `json:"IsExperimentalVersion,omitempty"`
+	Installed                bool                        `json:"Installed,omitempty"`
+	Modules                  []ModuleDescriptor          `json:"Modules,omitempty"`
+	Plugins                  []PluginReferenceDescriptor `json:"Plugins,omitempty"` // Plugin dependencies
+	SupportedTargetPlatforms []string                    `json:"SupportedTargetPlatforms,omitempty"`
+}
+
+// readProjectDescriptor reads the .uproject file
+func readProjectDescriptor(path string) (descriptor ProjectDescriptor, err error) {
+	err = readDescriptor(path, &descriptor)
+	return
+}
+
+// readPluginDescriptor reads the .uplugin file
+func readPluginDescriptor(path string) (descriptor PluginDescriptor, err error) {
+	err = readDescriptor(path, &descriptor)
+	return
+}
+
+// readDescriptor parses the JSON descriptor file
+func readDescriptor(path string, descriptor interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	// Descriptors saved by the editor on Windows may start with a byte order mark
+	b = []byte(strings.TrimPrefix(string(b), "\ufeff"))
+
+	if err = json.Unmarshal(b, descriptor); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// validatePackageDescriptor checks the descriptor of the user package before it is cooked, all problems are listed in the returned error
+func validatePackageDescriptor(job JobMetadata, path string) error {
+	descriptor, err := readPluginDescriptor(path)
+	if err != nil {
+		return fmt.Errorf("invalid package descriptor: %v", err)
+	}
+
+	var problems []string
+
+	// The plugin name is the descriptor file name and the root of the content paths, the FriendlyName is checked if the file name is unknown
+	verified := false
+	for _, f := range job.Files {
+		if f.Type == "uplugin" && f.OriginalPath != "" {
+			verified = true
+			if name := strings.TrimSuffix(filepath.Base(filepath.FromSlash(f.OriginalPath)), filepath.Ext(f.OriginalPath)); name != job.Package.Name {
+				problems = append(problems, fmt.Sprintf("descriptor %s does not match the package name %s", filepath.Base(f.OriginalPath), job.Package.Name))
+			}
+		}
+	}
+	if !verified {
+		if descriptor.FriendlyName == "" {
+			problems = append(problems, fmt.Sprintf("descriptor has neither a file name nor a FriendlyName to verify the package name %s", job.Package.Name))
+		} else if descriptor.FriendlyName != job.Package.Name {
+			problems = append(problems, fmt.Sprintf("FriendlyName %s does not match the package name %s", descriptor.FriendlyName, job.Package.Name))
+		}
+	}
+
+	if job.Package.Map != "" && !strings.HasPrefix(job.Package.Map, "/"+job.Package.Name+"/") {
+		problems = append(problems, fmt.Sprintf("map %s is not in the package content /%s/", job.Package.Map, job.Package.Name))
+	}
+
+	if !descriptor.CanContainContent {
+		problems = append(problems, "CanContainContent is not enabled")
+	}
+
+	if len(descriptor.Modules) > 0 {
+		var names []string
+		for _, m := range descriptor.Modules {
+			names = append(names, m.Name)
+		}
+		problems = append(problems, fmt.Sprintf("code modules are not allowed in content packages: %s", strings.Join(names, ", ")))
+	}
+
+	if engine := currentEngineVersion(); descriptor.EngineVersion != "" && engine != "" {
+		if compatible, err := engineVersionCompatible(descriptor.EngineVersion, engine); err != nil {
+			problems = append(problems, err.Error())
+		} else if !compatible {
+			problems = append(problems, fmt.Sprintf("EngineVersion %s is not compatible with the engine %s", descriptor.EngineVersion, engine))
+		}
+	}
+
+	if dependencies := disallowedPluginDependencies(descriptor); len(dependencies) > 0 {
+		problems = append(problems, fmt.Sprintf("plugin dependencies are not allowed: %s", strings.Join(dependencies, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid package descriptor %s.uplugin: %s", job.Package.Name, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// engineVersionCompatible checks that the content created with the plugin engine version can be loaded by the engine, the major versions must match and the plugin minor version must not be newer
+func engineVersionCompatible(pluginVersion string, engineVersion string) (bool, error) {
+	p, err := parseEngineVersion(pluginVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid EngineVersion %s", pluginVersion)
+	}
+
+	e, err := parseEngineVersion(engineVersion)
+	if err != nil {
+		// Source builds registered without a version can not be checked
+		Logger.Warningf("unknown version of the engine %s, the package engine version is not checked", engineVersion)
+		return true, nil
+	}
+
+	return p[0] == e[0] && p[1] <= e[1], nil
+}
+
+// parseEngineVersion parses the major and minor numbers of a version such as 5.1 or 5.1.0
+func parseEngineVersion(version string) (v [2]int, err error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return v, fmt.Errorf("invalid engine version %s", version)
+	}
+
+	for i := range v {
+		if v[i], err = strconv.Atoi(parts[i]); err != nil {
+			return v, fmt.Errorf("invalid engine version %s", version)
+		}
+	}
+
+	return v, nil
+}
+
+// disallowedPluginDependencies returns the enabled plugin dependencies which are neither allowed by the configuration nor enabled by the project
+func disallowedPluginDependencies(descriptor PluginDescriptor) (names []string) {
+	if len(descriptor.Plugins) == 0 {
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	if len(config.PackagePolicy.AllowedPlugins) > 0 {
+		for _, name := range config.PackagePolicy.AllowedPlugins {
+			allowed[strings.ToLower(name)] = true
+		}
+	} else if project, err := readProjectDescriptor(projectDescriptorPath()); err != nil {
+		Logger.Warningf("no package plugin dependencies are allowed: %v", err)
+	} else {
+		for _, p := range project.Plugins {
+			if p.Enabled {
+				allowed[strings.ToLower(p.Name)] = true
+			}
+		}
+	}
+
+	for _, p := range descriptor.Plugins {
+		if p.Enabled && !allowed[strings.ToLower(p.Name)] {
+			names = append(names, p.Name)
+		}
+	}
+
+	return
+}
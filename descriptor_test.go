@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePackageDescriptorName(t *testing.T) {
+	tests := []struct {
+		name         string
+		friendlyName string
+		originalPath string
+		err          string
+	}{
+		{"file name", "Forest Glade", "Forest.uplugin", ""},
+		{"other file name", "Forest", "Plugins/Desert/Desert.uplugin", "descriptor Desert.uplugin does not match the package name Forest"},
+		{"friendly name", "Forest", "", ""},
+		{"other friendly name", "Desert", "", "FriendlyName Desert does not match the package name Forest"},
+		{"no name", "", "", "descriptor has neither a file name nor a FriendlyName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Forest.uplugin")
+			descriptor := `{"FileVersion": 3, "FriendlyName": "` + tt.friendlyName + `", "CanContainContent": true}`
+			if err := os.WriteFile(path, []byte(descriptor), 0644); err != nil {
+				t.Fatalf("failed to write the descriptor: %v", err)
+			}
+
+			job := JobMetadata{
+				Package: &Package{Name: "Forest", Map: "/Forest/Maps/Forest"},
+				Entity:  Entity{Files: []File{{Type: "uplugin", OriginalPath: tt.originalPath}}},
+			}
+
+			err := validatePackageDescriptor(job, path)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
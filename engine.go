@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	goRuntime "runtime"
+	"strconv"
+	"strings"
+)
+
+// EngineConfig is an engine installation the project can be associated with
+type EngineConfig struct {
+	Id         string `json:"id"`                   // Engine association written to the project descriptor, a version such as 5.1 for installed engines or the {GUID} of a registered source build
+	Path       string `json:"path,omitempty"`       // Engine root directory containing the Engine directory
+	Version    string `json:"version,omitempty"`    // Engine version, read from Engine/Build/Build.version if empty, e.g. 5.1.1
+	EditorPath string `json:"editorPath,omitempty"` // Path to UnrealEditor-Cmd, default Engine/Binaries/<host platform>/UnrealEditor-Cmd under the engine root
+	UatPath    string `json:"uatPath,omitempty"`    // Path to AutomationTool, default Engine/Binaries/DotNET/AutomationTool/AutomationTool under the engine root
+	Default    bool   `json:"default,omitempty"`    // Used for the jobs which do not request an engine version, the first engine if none is marked
+}
+
+// EngineBuildVersion is the engine version file Engine/Build/Build.version
+type EngineBuildVersion struct {
+	MajorVersion         int    `json:"MajorVersion"`
+	MinorVersion         int    `json:"MinorVersion"`
+	PatchVersion         int    `json:"PatchVersion"`
+	Changelist           int    `json:"Changelist"`
+	CompatibleChangelist int    `json:"CompatibleChangelist"`
+	BranchName           string `json:"BranchName"`
+}
+
+// String returns the version as major.minor.patch
+func (v EngineBuildVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.MajorVersion, v.MinorVersion, v.PatchVersion)
+}
+
+// jobEngine is the engine selected for the current job
+var jobEngine *EngineConfig
+
+// defaultEngine is the engine configured with the VAT_UE_VERSION_CODE, VAT_EDITOR_PATH and VAT_UAT_PATH variables, used if there is no engine registry
+var defaultEngine *EngineConfig
+
+// engineAssociationRegexp matches the engine association of the project descriptor keeping its formatting
+var engineAssociationRegexp = regexp.MustCompile(`("EngineAssociation"\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+// fileVersionRegexp matches the first field of the project descriptor, the engine association is inserted after it if missing
+var fileVersionRegexp = regexp.MustCompile(`("FileVersion"\s*:\s*\d+\s*,)(\r?\n?)(\s*)`)
+
+// projectDescriptorPath returns the path to the project descriptor
+func projectDescriptorPath() string {
+	return filepath.Join(projectDir, projectName+".uproject")
+}
+
+// resolveEngineAssociation resolves the engine version, id or installation path against the engine registry and returns the engine association.
+// Values are used as is if there is no engine registry.
+func resolveEngineAssociation(versionIdOrPath string) (string, error) {
+	if len(config.Engines) == 0 {
+		Logger.Warningf("no engine registry configured, associating the project with %s as is", versionIdOrPath)
+		return versionIdOrPath, nil
+	}
+
+	for _, e := range config.Engines {
+		if strings.EqualFold(e.Id, versionIdOrPath) {
+			return e.Id, nil
+		}
+	}
+
+	for _, e := range config.Engines {
+		if e.Path != "" && sameEnginePath(e.Path, versionIdOrPath) {
+			return e.Id, nil
+		}
+	}
+
+	for _, e := range config.Engines {
+		if e.Version != "" && engineVersionMatches(e.Version, versionIdOrPath) {
+			return e.Id, nil
+		}
+	}
+
+	var known []string
+	for _, e := range config.Engines {
+		known = append(known, e.Id)
+	}
+
+	return "", fmt.Errorf("engine %s is not in the engine registry, known engines: %s", versionIdOrPath, strings.Join(known, ", "))
+}
+
+// sameEnginePath reports whether both paths point to the same engine root directory
+func sameEnginePath(a string, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if os.PathSeparator == '\\' {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// engineVersionMatches reports whether the engine version starts with the requested version components, e.g. 5.1.1 matches 5.1
+func engineVersionMatches(version string, requested string) bool {
+	v, r := strings.Split(version, "."), strings.Split(requested, ".")
+	if len(r) > len(v) {
+		return false
+	}
+
+	for i := range r {
+		if _, err := strconv.Atoi(r[i]); err != nil || v[i] != r[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readEngineBuildVersion reads the version of the engine installed at the root directory
+func readEngineBuildVersion(root string) (version EngineBuildVersion, err error) {
+	path := filepath.Join(root, "Engine", "Build", "Build.version")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return version, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if err = json.Unmarshal(b, &version); err != nil {
+		return version, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return version, nil
+}
+
+// loadEngineRegistry fills the engine versions and the tool paths not set in the configuration
+func loadEngineRegistry(engines []EngineConfig) error {
+	for i := range engines {
+		e := &engines[i]
+		if e.Id == "" {
+			return fmt.Errorf("engine %d has no id", i+1)
+		}
+
+		if e.Path != "" {
+			if e.EditorPath == "" {
+				e.EditorPath = filepath.Join(e.Path, "Engine", "Binaries", hostBinariesPlatform(), "UnrealEditor-Cmd"+hostExecutableExt())
+			}
+			if e.UatPath == "" {
+				e.UatPath = filepath.Join(e.Path, "Engine", "Binaries", "DotNET", "AutomationTool", "AutomationTool"+hostExecutableExt())
+			}
+
+			version, err := readEngineBuildVersion(e.Path)
+			if err != nil {
+				if e.Version == "" {
+					return fmt.Errorf("engine %s has no version: %v", e.Id, err)
+				}
+			} else if e.Version == "" {
+				e.Version = version.String()
+			} else if !engineVersionMatches(version.String(), e.Version) {
+				Logger.Warningf("engine %s is configured as version %s but %s is installed at %s", e.Id, e.Version, version, e.Path)
+			}
+		} else if e.Version == "" {
+			return fmt.Errorf("engine %s has neither a version nor a path to read it from", e.Id)
+		}
+
+		if e.EditorPath == "" || e.UatPath == "" {
+			return fmt.Errorf("engine %s has no path, editorPath or uatPath", e.Id)
+		}
+	}
+
+	return nil
+}
+
+// setDefaultEngine sets the engine used if there is no engine registry from the current engine id and tool paths, the version is read from the engine installed at the editor path
+func setDefaultEngine() {
+	e := &EngineConfig{Id: ueVersionCode, EditorPath: editorPath, UatPath: uatPath, Default: true}
+
+	if editorPath != "" {
+		// The editor is in Engine/Binaries/<platform>
+		e.Path = filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(editorPath))))
+		if version, err := readEngineBuildVersion(e.Path); err == nil {
+			e.Version = version.String()
+		}
+	}
+
+	defaultEngine = e
+}
+
+// availableEngines returns the engine registry, or the default engine if there is no registry
+func availableEngines() []EngineConfig {
+	if len(config.Engines) > 0 {
+		return config.Engines
+	}
+
+	if defaultEngine != nil {
+		return []EngineConfig{*defaultEngine}
+	}
+
+	return nil
+}
+
+// availableEngineVersions returns the distinct versions of the available engines advertised when fetching jobs
+func availableEngineVersions() (versions []string) {
+	seen := map[string]bool{}
+	for _, e := range availableEngines() {
+		if e.Version != "" && !seen[e.Version] {
+			seen[e.Version] = true
+			versions = append(versions, e.Version)
+		}
+	}
+
+	return
+}
+
+// jobEngineVersion returns the engine version requested by the job release or package, empty if not requested
+func jobEngineVersion(job JobMetadata) string {
+	if job.Release != nil && job.Release.EngineVersion != "" {
+		return job.Release.EngineVersion
+	}
+
+	if job.Package != nil && job.Package.EngineVersion != "" {
+		return job.Package.EngineVersion
+	}
+
+	return ""
+}
+
+// selectJobEngine returns the engine requested by the job, matched by id or by version prefix, or the default engine
+func selectJobEngine(job JobMetadata) (*EngineConfig, error) {
+	engines := availableEngines()
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("no engines configured")
+	}
+
+	requested := jobEngineVersion(job)
+	if requested == "" {
+		for i := range engines {
+			if engines[i].Default {
+				return &engines[i], nil
+			}
+		}
+		return &engines[0], nil
+	}
+
+	for i := range engines {
+		if strings.EqualFold(engines[i].Id, requested) {
+			return &engines[i], nil
+		}
+	}
+
+	for i := range engines {
+		if engines[i].Version != "" && engineVersionMatches(engines[i].Version, requested) {
+			return &engines[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("engine %s requested by the job is not installed, available engines: %s", requested, strings.Join(availableEngineVersions(), ", "))
+}
+
+// useJobEngine selects the engine of the job, the project is associated with it and its editor and AutomationTool are used by the job
+func useJobEngine(job JobMetadata) error {
+	e, err := selectJobEngine(job)
+	if err != nil {
+		return err
+	}
+
+	if e.Version != "" && e.Version != e.Id {
+		Logger.Infof("using engine %s %s", e.Id, e.Version)
+	} else {
+		Logger.Infof("using engine %s", e.Id)
+	}
+
+	jobEngine = e
+	ueVersionCode = e.Id
+	editorPath = e.EditorPath
+	uatPath = e.UatPath
+
+	return nil
+}
+
+// currentEngineVersion returns the version of the engine used by the job, the engine id if the version is unknown
+func currentEngineVersion() string {
+	if jobEngine != nil && jobEngine.Version != "" {
+		return jobEngine.Version
+	}
+
+	return ueVersionCode
+}
+
+// hostBinariesPlatform returns the engine binaries directory name of this machine
+func hostBinariesPlatform() string {
+	switch goRuntime.GOOS {
+	case "windows":
+		return "Win64"
+	case "darwin":
+		return "Mac"
+	}
+
+	return "Linux"
+}
+
+// hostExecutableExt returns the executable file extension of this machine
+func hostExecutableExt() string {
+	if goRuntime.GOOS == "windows" {
+		return ".exe"
+	}
+
+	return ""
+}
+
+// readProjectEngineAssociation reads the engine association of the project descriptor, empty if the project is not associated
+func readProjectEngineAssociation(path string) (string, error) {
+	descriptor, err := readProjectDescriptor(path)
+	if err != nil {
+		return "", err
+	}
+
+	return descriptor.EngineAssociation, nil
+}
+
+// writeProjectEngineAssociation rewrites the engine association of the project descriptor keeping the rest of the file intact
+func writeProjectEngineAssociation(path string, association string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if !json.Valid(b) {
+		return fmt.Errorf("failed to parse %s: invalid JSON", path)
+	}
+
+	value, err := json.Marshal(association)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the engine association: %v", err)
+	}
+
+	var out []byte
+	if engineAssociationRegexp.Match(b) {
+		out = engineAssociationRegexp.ReplaceAllFunc(b, func(m []byte) []byte {
+			prefix := engineAssociationRegexp.FindSubmatch(m)[1]
+			return append(append([]byte{}, prefix...), value...)
+		})
+	} else if loc := fileVersionRegexp.FindSubmatchIndex(b); loc != nil {
+		// Insert after FileVersion using the same line break and indentation
+		lineBreak, indent := b[loc[4]:loc[5]], b[loc[6]:loc[7]]
+		field := fmt.Sprintf(`"EngineAssociation": %s,`, value)
+		out = append(append(append(append(append([]byte{}, b[:loc[3]]...), lineBreak...), indent...), field...), b[loc[3]:]...)
+	} else {
+		return fmt.Errorf("failed to find where to write the engine association in %s", path)
+	}
+
+	if err = os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// switchProjectEngineVersion associates the project with the engine version, id or installation path resolved against the engine registry
+func switchProjectEngineVersion(versionIdOrPath string) error {
+	association, err := resolveEngineAssociation(versionIdOrPath)
+	if err != nil {
+		return err
+	}
+
+	path := projectDescriptorPath()
+	current, err := readProjectEngineAssociation(path)
+	if err != nil {
+		return err
+	}
+
+	if current == association {
+		Logger.Infof("project is already associated with engine %s", association)
+		return nil
+	}
+
+	Logger.Infof("switching engine association from %s to %s", current, association)
+
+	return writeProjectEngineAssociation(path, association)
+}
+
+//region Restore the engine association after the job
+
+// jobEngineAssociation remembers the project engine association at the start of the job to restore it when the job ends
+type jobEngineAssociation struct {
+	path     string
+	original string
+}
+
+// startJobEngineAssociation reads the project engine association at the start of the job, returns nil if the project descriptor can not be read
+func startJobEngineAssociation(job JobMetadata) *jobEngineAssociation {
+	path := projectDescriptorPath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		// e.g. launcher workers without a project
+		return nil
+	}
+
+	original, err := readProjectEngineAssociation(path)
+	if err != nil {
+		Logger.Warningf("failed to read the project engine association, it will not be restored after job %s: %v", job.Id, err)
+		return nil
+	}
+
+	return &jobEngineAssociation{path: path, original: original}
+}
+
+// Close restores the engine association if the job changed it
+func (a *jobEngineAssociation) Close() {
+	if a == nil {
+		return
+	}
+
+	current, err := readProjectEngineAssociation(a.path)
+	if err != nil {
+		Logger.Errorf("failed to restore the project engine association: %v", err)
+		return
+	}
+
+	if current == a.original {
+		return
+	}
+
+	Logger.Infof("restoring engine association %s", a.original)
+	if err = writeProjectEngineAssociation(a.path, a.original); err != nil {
+		Logger.Errorf("failed to restore the project engine association: %v", err)
+	}
+}
+
+//endregion
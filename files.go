@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// filesPreviewOptions are the options of the files preview command
+type filesPreviewOptions struct {
+	dir           string // Staging directory for releases or project directory for SDK
+	platform      string // Job platform, e.g. Win64
+	deployment    string // Job deployment, e.g. Client, Server or SDK
+	configuration string // Job configuration, e.g. Shipping
+	excludedOnly  bool   // Print excluded files only
+	includedOnly  bool   // Print included files only
+}
+
+// newFilesCmd creates the command group to inspect files selected for releases and SDKs
+func newFilesCmd() *cobra.Command {
+	filesCmd := &cobra.Command{
+		Use:   "files",
+		Short: "Inspect files selected for releases and SDKs",
+		// Does not need the API
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+	}
+
+	var o filesPreviewOptions
+	previewCmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Show files a release or an SDK would contain and the rules deciding each one",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return previewFiles(o)
+		},
+	}
+
+	previewCmd.Flags().StringVar(&o.dir, "dir", "", "staging directory of a release or project directory of an SDK, defaults to the one used by the job")
+	previewCmd.Flags().StringVar(&o.platform, "platform", "", "job platform, e.g. Win64, Linux, Mac")
+	previewCmd.Flags().StringVar(&o.deployment, "deployment", "Client", "job deployment: Client, Server or SDK")
+	previewCmd.Flags().StringVar(&o.configuration, "configuration", "Shipping", "job configuration")
+	previewCmd.Flags().BoolVar(&o.excludedOnly, "excluded", false, "print excluded files only")
+	previewCmd.Flags().BoolVar(&o.includedOnly, "included", false, "print included files only")
+	_ = previewCmd.MarkFlagRequired("platform")
+
+	filesCmd.AddCommand(previewCmd)
+
+	return filesCmd
+}
+
+// previewFiles runs the release or SDK file selection for the platform and deployment and prints the decision for each file
+func previewFiles(o filesPreviewOptions) error {
+	projectDir = os.Getenv("VAT_PROJECT_DIR")
+
+	job := JobMetadata{Platform: o.platform, Deployment: o.deployment, Configuration: o.configuration}
+	if !SupportedJobDeployments[job.Deployment] {
+		return fmt.Errorf("unsupported deployment: %s", job.Deployment)
+	}
+
+	var (
+		selection []fileSelection
+		err       error
+	)
+
+	if o.dir == "" && projectDir != "" {
+		if job.Deployment == "SDK" {
+			o.dir = projectDir
+		} else {
+			o.dir = filepath.Join(projectDir, "Saved", "StagedBuilds", getPlatformName(job))
+		}
+	}
+
+	if o.dir == "" {
+		return fmt.Errorf("no directory to preview, use --dir or set VAT_PROJECT_DIR")
+	}
+
+	if job.Deployment == "SDK" {
+		include, err1 := getSdkIncludeFiles(job)
+		if err1 != nil {
+			return err1
+		}
+
+		selection, err = selectSdkFilesRecursive(o.dir, include)
+	} else {
+		ignore, err1 := getReleaseIgnoredFiles(job)
+		if err1 != nil {
+			return err1
+		}
+
+		selection, err = selectFilesRecursive(o.dir, ignore, true)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to select files in %s: %v", o.dir, err)
+	}
+
+	var (
+		includedCount, excludedCount int
+		includedSize, excludedSize   int64
+	)
+
+	fmt.Printf("%s %s %s files in %s\n", job.Platform, job.Deployment, job.Configuration, o.dir)
+	for _, f := range selection {
+		rule := "no rule"
+		if f.Rule != nil {
+			rule = f.Rule.String()
+		}
+
+		if f.Included {
+			includedCount++
+			includedSize += f.Size
+			if !o.excludedOnly {
+				fmt.Printf("+ %s (%s) %s\n", filepath.ToSlash(f.Path), formatByteSize(f.Size), rule)
+			}
+		} else {
+			excludedCount++
+			excludedSize += f.Size
+			if !o.includedOnly {
+				fmt.Printf("- %s (%s) %s\n", filepath.ToSlash(f.Path), formatByteSize(f.Size), rule)
+			}
+		}
+	}
+
+	fmt.Printf("included: %d files, %s\n", includedCount, formatByteSize(includedSize))
+	fmt.Printf("excluded: %d files, %s\n", excludedCount, formatByteSize(excludedSize))
+
+	return nil
+}
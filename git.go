@@ -88,6 +88,12 @@ func gitPull(r *git.Repository) error {
 		return fmt.Errorf("invalid repository")
 	}
 
+	// Simulated projects have no remote
+	if simulation != nil {
+		Logger.Infof("simulation, skipping git pull")
+		return nil
+	}
+
 	w, err := r.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get a git worktree: %v", err)
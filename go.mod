@@ -10,6 +10,7 @@ require (
 	github.com/masterminds/semver v1.5.0
 	github.com/mholt/archiver/v4 v4.0.0-alpha.7
 	github.com/sirupsen/logrus v1.7.0
+	github.com/spf13/cobra v1.6.1
 )
 
 require (
@@ -36,7 +37,6 @@ require (
 	github.com/nwaples/rardecode/v2 v2.0.0-beta.2 // indirect
 	github.com/pierrec/lz4/v4 v4.1.14 // indirect
 	github.com/sergi/go-diff v1.2.0 // indirect
-	github.com/spf13/cobra v1.6.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/therootcompany/xz v1.0.1 // indirect
 	github.com/ulikunitz/xz v0.5.10 // indirect
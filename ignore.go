@@ -0,0 +1,357 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreSectionRegexp matches section headers such as "[platform=Linux deployment=Server]" or "[platform=Win64,Mac]",
+// an empty header "[]" ends the previous section
+var ignoreSectionRegexp = regexp.MustCompile(`^\[((?:\s*\w+=[^\s\]=]+)*)\s*\]$`)
+
+// ignoreRule is a single gitignore-style pattern
+type ignoreRule struct {
+	Pattern  string // Original pattern as written in the file
+	Line     int    // Line number in the file, 1-based
+	Negate   bool   // Pattern starts with "!" and re-includes matching paths
+	DirOnly  bool   // Pattern ends with "/" and matches directories only
+	Anchored bool   // Pattern contains a "/" and is matched relative to the root instead of at any level
+	Section  string // Section header the pattern belongs to, empty if the pattern applies unconditionally
+	regexp   *regexp.Regexp
+	segments []*regexp.Regexp // Path segments of an anchored pattern, nil for "**"
+
+	conditions map[string][]string // Section conditions, e.g. platform: [Linux, Mac]
+}
+
+// String returns the rule in the form used in logs, e.g. "12: *.pdb [platform=Win64]"
+func (r *ignoreRule) String() string {
+	if r.Section != "" {
+		return fmt.Sprintf("%d: %s %s", r.Line, r.Pattern, r.Section)
+	}
+	return fmt.Sprintf("%d: %s", r.Line, r.Pattern)
+}
+
+// ignoreRules is an ordered list of gitignore-style patterns, the last matching pattern wins
+type ignoreRules []*ignoreRule
+
+// parseIgnoreRules parses gitignore-style patterns skipping blank lines and comments.
+// Patterns following a section header such as "[platform=Linux deployment=Server]" only apply when all the section conditions are met,
+// a condition may list several values separated by commas.
+func parseIgnoreRules(data string) (ignoreRules, error) {
+	var (
+		rules      ignoreRules
+		section    string
+		conditions map[string][]string
+	)
+
+	for i, line := range strings.Split(data, "\n") {
+		if m := ignoreSectionRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			section = strings.TrimSpace(line)
+			conditions = map[string][]string{}
+			for _, condition := range strings.Fields(m[1]) {
+				kv := strings.SplitN(condition, "=", 2)
+				conditions[strings.ToLower(kv[0])] = strings.Split(kv[1], ",")
+			}
+			continue
+		}
+
+		rule, err := parseIgnoreRule(line, i+1)
+		if err != nil {
+			return nil, err
+		}
+
+		if rule != nil {
+			rule.Section = section
+			rule.conditions = conditions
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// loadIgnoreRules reads gitignore-style rules from the file in the project directory, falling back to the working directory
+// for older setups, returns no rules if the file does not exist
+func loadIgnoreRules(name string) (ignoreRules, error) {
+	var data []byte
+	var err error
+	for _, p := range []string{filepath.Join(projectDir, name), name} {
+		data, err = os.ReadFile(p)
+		if err == nil {
+			Logger.Infof("using rules from %s", p)
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read from %s file: %v", p, err)
+		}
+	}
+
+	if err != nil {
+		Logger.Warningf("no %s file found in %s", name, projectDir)
+		return nil, nil
+	}
+
+	rules, err := parseIgnoreRules(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s file: %v", name, err)
+	}
+
+	return rules, nil
+}
+
+// jobRuleValues returns values used to select sections of the rule files for the job
+func jobRuleValues(job JobMetadata) map[string]string {
+	return map[string]string{
+		"platform":      job.Platform,
+		"deployment":    job.Deployment,
+		"configuration": job.Configuration,
+	}
+}
+
+// applies reports whether all the section conditions of the rule are met by the values
+func (r *ignoreRule) applies(values map[string]string) bool {
+	for key, allowed := range r.conditions {
+		found := false
+		for _, v := range allowed {
+			if strings.EqualFold(v, values[key]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Select returns the rules applying to the values, e.g. platform and deployment of the job
+func (rules ignoreRules) Select(values map[string]string) ignoreRules {
+	var selected ignoreRules
+	for _, r := range rules {
+		if r.applies(values) {
+			selected = append(selected, r)
+		}
+	}
+
+	return selected
+}
+
+// parseIgnoreRule parses a single pattern line, returns nil for blank lines and comments
+func parseIgnoreRule(line string, lineNumber int) (*ignoreRule, error) {
+	line = strings.TrimRight(line, "\r")
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	rule := &ignoreRule{Pattern: line, Line: lineNumber}
+
+	pattern := line
+	if strings.HasPrefix(pattern, "!") {
+		rule.Negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, "\\!") || strings.HasPrefix(pattern, "\\#") {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.DirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	if strings.Contains(pattern, "/") {
+		rule.Anchored = true
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid pattern at line %d: %s", lineNumber, line)
+	}
+
+	expr := globToRegexp(pattern)
+	if !rule.Anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	var err error
+	if rule.regexp, err = regexp.Compile("^" + expr + "$"); err != nil {
+		return nil, fmt.Errorf("invalid pattern at line %d: %s: %v", lineNumber, line, err)
+	}
+
+	if rule.Anchored {
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "**" {
+				rule.segments = append(rule.segments, nil)
+				continue
+			}
+			rule.segments = append(rule.segments, regexp.MustCompile("^"+globToRegexp(segment)+"$"))
+		}
+	}
+
+	return rule, nil
+}
+
+// globToRegexp converts a gitignore glob to a regular expression matching slash separated paths
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				atStart := i == 0 || glob[i-1] == '/'
+				atEnd := i+2 == len(glob) || glob[i+2] == '/'
+				if atStart && atEnd {
+					if i+2 == len(glob) {
+						// Trailing "/**" matches everything inside
+						b.WriteString(".*")
+						i++
+					} else {
+						// Leading "**/" and inner "/**/" match zero or more directories
+						b.WriteString("(?:.*/)?")
+						i += 2
+					}
+					continue
+				}
+				// Other consecutive asterisks are regular asterisks
+				for i+1 < len(glob) && glob[i+1] == '*' {
+					i++
+				}
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString("\\[")
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
+
+// match reports whether the rule matches the slash separated path relative to the root
+func (r *ignoreRule) match(relPath string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+
+	return r.regexp.MatchString(relPath)
+}
+
+// Match returns the last rule matching the slash separated path relative to the root or nil if none matches,
+// the path is ignored if the returned rule is not negated
+func (rules ignoreRules) Match(relPath string, isDir bool) *ignoreRule {
+	relPath = strings.Trim(path.Clean(relPath), "/")
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].match(relPath, isDir) {
+			return rules[i]
+		}
+	}
+
+	return nil
+}
+
+// MatchWithParents returns the last rule matching the path or any of its parent directories, nil if nothing matched.
+// Used for include lists where listing a directory includes everything inside it, a later negated rule excludes the path again.
+func (rules ignoreRules) MatchWithParents(relPath string) *ignoreRule {
+	relPath = strings.Trim(path.Clean(relPath), "/")
+	parts := strings.Split(relPath, "/")
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].match(relPath, false) {
+			return rules[i]
+		}
+		for j := 1; j < len(parts); j++ {
+			if rules[i].match(strings.Join(parts[:j], "/"), true) {
+				return rules[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// mayMatchInside reports whether the rule may match a path inside the directory
+func (r *ignoreRule) mayMatchInside(dir string) bool {
+	if !r.Anchored {
+		return true
+	}
+
+	parts := strings.Split(dir, "/")
+	for i, part := range parts {
+		if i >= len(r.segments) {
+			return false
+		}
+		if r.segments[i] == nil {
+			return true
+		}
+		if !r.segments[i].MatchString(part) {
+			return false
+		}
+	}
+
+	return len(r.segments) > len(parts)
+}
+
+// MayInclude reports whether the directory or anything inside it may be included by the rules used as an include list,
+// lets the walk skip directories no rule can include anything from
+func (rules ignoreRules) MayInclude(dir string) bool {
+	dir = strings.Trim(path.Clean(dir), "/")
+	if dir == "" || dir == "." {
+		return true
+	}
+
+	parts := strings.Split(dir, "/")
+	for _, r := range rules {
+		if r.Negate {
+			continue
+		}
+		if r.mayMatchInside(dir) {
+			return true
+		}
+		for j := 1; j <= len(parts); j++ {
+			if r.match(strings.Join(parts[:j], "/"), true) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Ignored reports whether the path itself is ignored, parent directories are not checked as the walk prunes them
+func (rules ignoreRules) Ignored(relPath string, isDir bool) bool {
+	rule := rules.Match(relPath, isDir)
+	return rule != nil && !rule.Negate
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func mustParseIgnoreRules(t *testing.T, data string) ignoreRules {
+	t.Helper()
+
+	rules, err := parseIgnoreRules(data)
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	return rules
+}
+
+func mustLoadRuleFile(t *testing.T, name string) ignoreRules {
+	t.Helper()
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+
+	return mustParseIgnoreRules(t, string(b))
+}
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"star matches a name", "*.pdb", "Metaverse.pdb", false, true},
+		{"star matches at any level", "*.pdb", "Binaries/Win64/Metaverse.pdb", false, true},
+		{"star does not cross directories", "Binaries/*.pdb", "Binaries/Win64/Metaverse.pdb", false, false},
+		{"star matches within a directory", "Binaries/*.pdb", "Binaries/Metaverse.pdb", false, true},
+		{"question mark matches a character", "file?.txt", "file1.txt", false, true},
+		{"character class", "file[0-9].txt", "fileA.txt", false, false},
+		{"leading double star", "**/Saved", "Plugins/VeGame/Saved", true, true},
+		{"leading double star matches the root", "**/Saved", "Saved", true, true},
+		{"inner double star matches no directories", "Plugins/**/Binaries", "Plugins/Binaries", true, true},
+		{"inner double star matches nested directories", "Plugins/**/Binaries", "Plugins/VeGame/Sub/Binaries", true, true},
+		{"trailing double star matches inside", "Samples/**", "Samples/PixelStreaming/run.sh", false, true},
+		{"trailing double star does not match the directory", "Samples/**", "Samples", true, false},
+		{"unanchored name at any level", "README.md", "Plugins/VeGame/README.md", false, true},
+		{"anchored with a leading slash", "/README.md", "Plugins/VeGame/README.md", false, false},
+		{"anchored with a leading slash at the root", "/README.md", "README.md", false, true},
+		{"anchored with an inner slash", "Engine/Extras", "Metaverse/Engine/Extras", true, false},
+		{"directory only matches directories", "Samples/", "Samples", true, true},
+		{"directory only skips files", "Samples/", "Samples", false, false},
+		{"negation re-includes", "*.txt\n!keep.txt", "keep.txt", false, false},
+		{"negation of other paths", "*.txt\n!keep.txt", "drop.txt", false, true},
+		{"last rule wins", "!keep.txt\n*.txt", "keep.txt", false, true},
+		{"comments are skipped", "# *.txt\n", "notes.txt", false, false},
+		{"escaped hash", "\\#notes", "#notes", false, true},
+		{"escaped exclamation mark", "\\!important", "!important", false, true},
+		{"blank lines are skipped", "\n\n*.log\n\n", "uat.log", false, true},
+		{"trailing spaces are trimmed", "*.log   ", "uat.log", false, true},
+		{"windows line endings", "*.log\r\n*.pdb\r\n", "Metaverse.pdb", false, true},
+		{"paths are cleaned", "/Binaries/", "./Binaries/", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := mustParseIgnoreRules(t, tt.rules)
+			if got := rules.Ignored(tt.path, tt.isDir); got != tt.ignored {
+				t.Errorf("Ignored(%q, %v) with rules %q = %v, want %v", tt.path, tt.isDir, tt.rules, got, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestIgnoreRulesMatchRule(t *testing.T) {
+	rules := mustParseIgnoreRules(t, "# Comment\n\n*.txt\n!keep.txt\n")
+
+	rule := rules.Match("keep.txt", false)
+	if rule == nil {
+		t.Fatal("expected a matching rule")
+	}
+	if !rule.Negate || rule.Line != 4 || rule.Pattern != "!keep.txt" {
+		t.Errorf("unexpected rule %s, negate %v", rule, rule.Negate)
+	}
+
+	if rule := rules.Match("keep.bin", false); rule != nil {
+		t.Errorf("unexpected rule %s", rule)
+	}
+}
+
+func TestIgnoreRulesInvalid(t *testing.T) {
+	for _, data := range []string{"/", "!", "!/"} {
+		if _, err := parseIgnoreRules(data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestIgnoreRulesSections(t *testing.T) {
+	rules := mustParseIgnoreRules(t, "*.log\n[platform=Linux,Mac deployment=Server]\n*.debug\n[platform=Win64]\n*.pdb\n[]\n*.tmp\n")
+
+	tests := []struct {
+		platform   string
+		deployment string
+		path       string
+		ignored    bool
+	}{
+		{"Linux", "Server", "MetaverseServer.debug", true},
+		{"Mac", "Server", "MetaverseServer.debug", true},
+		{"Linux", "Client", "Metaverse.debug", false},
+		{"Win64", "Server", "MetaverseServer.debug", false},
+		{"Win64", "Client", "Metaverse.pdb", true},
+		{"linux", "server", "MetaverseServer.debug", true},
+		{"Linux", "Client", "Metaverse.pdb", false},
+		{"Linux", "Client", "uat.log", true},
+		{"Linux", "Client", "cache.tmp", true},
+		{"Win64", "Server", "cache.tmp", true},
+	}
+
+	for _, tt := range tests {
+		selected := rules.Select(map[string]string{"platform": tt.platform, "deployment": tt.deployment})
+		if got := selected.Ignored(tt.path, false); got != tt.ignored {
+			t.Errorf("Ignored(%q) for %s %s = %v, want %v", tt.path, tt.platform, tt.deployment, got, tt.ignored)
+		}
+	}
+
+	if rule := rules.Match("Metaverse.pdb", false); rule == nil || rule.String() != "5: *.pdb [platform=Win64]" {
+		t.Errorf("unexpected rule %v", rule)
+	}
+}
+
+func TestIgnoreRulesMatchWithParents(t *testing.T) {
+	rules := mustParseIgnoreRules(t, "Config/Layouts/\nPlugins/VeGame/Config/\nPlugins/VeGame/VeGame.uplugin\n!Plugins/VeGame/Config/Secret.ini\nContent/Splash/Splash.uasset\n!Config/Layouts/Private/\nConfig/Layouts/Private/Shared.ini\n")
+
+	tests := []struct {
+		path     string
+		included bool
+	}{
+		{"Config/Layouts/Default.ini", true},
+		{"Config/Layouts/Nested/Layout.ini", true},
+		{"Config/DefaultGame.ini", false},
+		{"Plugins/VeGame/Config/DefaultVeGame.ini", true},
+		{"Plugins/VeGame/VeGame.uplugin", true},
+		{"Plugins/VeGame/Source/VeGame.cpp", false},
+		{"Content/Splash/Splash.uasset", true},
+		{"Content/Splash/Other.uasset", false},
+		// The last matching rule wins whether it matches the path or a parent directory
+		{"Plugins/VeGame/Config/Secret.ini", false},
+		{"Config/Layouts/Private/Layout.ini", false},
+		{"Config/Layouts/Private/Shared.ini", true},
+	}
+
+	for _, tt := range tests {
+		rule := rules.MatchWithParents(tt.path)
+		if got := rule != nil && !rule.Negate; got != tt.included {
+			t.Errorf("MatchWithParents(%q) = %v, want %v", tt.path, rule, tt.included)
+		}
+	}
+}
+
+func TestIgnoreRulesMayInclude(t *testing.T) {
+	rules := mustParseIgnoreRules(t, "Config/Layouts/\nPlugins/*/Config/\nContent/**/Splash.uasset\n*.uproject\n!Config/Layouts/Private/\n")
+
+	tests := []struct {
+		dir      string
+		included bool
+	}{
+		{"Config", true},
+		{"Config/Layouts", true},
+		{"Config/Layouts/Private", true},
+		{"Plugins/VeGame", true},
+		{"Plugins/VeGame/Config/Nested", true},
+		{"Plugins/VeGame/Source", true},
+		{"Content/Maps/Lobby", true},
+		{"Source/Metaverse", true},
+	}
+
+	for _, tt := range tests {
+		if got := rules.MayInclude(tt.dir); got != tt.included {
+			t.Errorf("MayInclude(%q) = %v, want %v", tt.dir, got, tt.included)
+		}
+	}
+
+	// Without unanchored rules directories outside of the listed paths are skipped
+	rules = mustParseIgnoreRules(t, "Config/Layouts/\nPlugins/*/Config/\nContent/**/Splash.uasset\n")
+
+	tests = []struct {
+		dir      string
+		included bool
+	}{
+		{"Config", true},
+		{"Config/Tags", false},
+		{"Config/Layouts/Private", true},
+		{"Plugins/VeGame/Config/Nested", true},
+		{"Plugins/VeGame/Source", false},
+		{"Content/Maps/Lobby", true},
+		{"Source/Metaverse", false},
+		{"Binaries", false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.MayInclude(tt.dir); got != tt.included {
+			t.Errorf("MayInclude(%q) = %v, want %v", tt.dir, got, tt.included)
+		}
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	rules := mustLoadRuleFile(t, ".veverse-automation-ignore")
+
+	tests := []struct {
+		platform   string
+		deployment string
+		path       string
+		isDir      bool
+		ignored    bool
+	}{
+		{"Linux", "Client", ".git", true, true},
+		{"Linux", "Client", ".gitignore", false, true},
+		{"Linux", "Client", "Metaverse/.DS_Store", false, true},
+		{"Linux", "Client", "Samples/PixelStreaming", true, true},
+		{"Linux", "Client", "Engine/Extras/GPUDumpViewer/OpenGPUDumpViewer.sh", false, true},
+		{"Linux", "Client", "Metaverse/Engine/Extras/GPUDumpViewer/OpenGPUDumpViewer.sh", false, false},
+		{"Linux", "Client", "Manifest_UFSFiles_Linux.txt", false, true},
+		{"Linux", "Client", "Manifest_UFSFiles_Win64.txt", false, false},
+		{"Linux", "Client", "Metaverse/Binaries/Linux/Metaverse", false, false},
+		{"Linux", "Server", "Metaverse/Binaries/Linux/MetaverseServer.debug", false, true},
+		{"Linux", "Client", "Metaverse/Binaries/Linux/MetaverseServer.debug", false, false},
+		{"Win64", "Server", "Metaverse/Binaries/Win64/MetaverseServer.pdb", false, true},
+		{"Win64", "Client", "Metaverse/Binaries/Win64/MetaverseServer.pdb", false, false},
+		{"Win64", "Client", "Metaverse/Binaries/Win64/Metaverse.pdb", false, true},
+		{"Win64", "Client", "Manifest_DebugFiles_Win64.txt", false, true},
+		{"Win64", "Client", "Metaverse.exe", false, false},
+	}
+
+	for _, tt := range tests {
+		selected := rules.Select(map[string]string{"platform": tt.platform, "deployment": tt.deployment})
+		if got := selected.Ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Ignored(%q) for %s %s = %v, want %v", tt.path, tt.platform, tt.deployment, got, tt.ignored)
+		}
+	}
+}
+
+func TestSdkIncludeFile(t *testing.T) {
+	rules := mustLoadRuleFile(t, ".veverse-automation-sdk-include")
+
+	tests := []struct {
+		platform string
+		path     string
+		included bool
+	}{
+		{"Win64", "Metaverse.uproject", true},
+		{"Win64", "Config/DefaultEngine.ini", true},
+		{"Win64", "Config/Layouts/Default.ini", true},
+		{"Win64", "Content/Splash/Splash.uasset", true},
+		{"Win64", "Content/Maps/Lobby.umap", false},
+		{"Win64", "Plugins/VeGame/VeGame.uplugin", true},
+		{"Win64", "Plugins/VeGame/Source/VeGame/VeGame.Build.cs", false},
+		{"Win64", "Binaries/Win64/UnrealEditor-Metaverse.dll", true},
+		{"Linux", "Binaries/Win64/UnrealEditor-Metaverse.dll", false},
+		{"Mac", "Binaries/Win64/UnrealEditor-Metaverse.dll", false},
+		{"Win64", "Plugins/VeSentry/Source/ThirdParty/SentryLibrary/Windows/sentry.dll", true},
+		{"Win64", "Source/Metaverse/Metaverse.cpp", false},
+	}
+
+	for _, tt := range tests {
+		selected := rules.Select(map[string]string{"platform": tt.platform, "deployment": "SDK"})
+		rule := selected.MatchWithParents(tt.path)
+		if got := rule != nil && !rule.Negate; got != tt.included {
+			t.Errorf("MatchWithParents(%q) for %s = %v, want %v", tt.path, tt.platform, rule, tt.included)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 )
 
 // updateJobStatus updates the job status using status code and error message
@@ -57,16 +59,14 @@ func updateJobStatus(job JobMetadata, statusCode int, message string) error {
 	return nil
 }
 
-// reportJobLog reports the job log using warnings and errors
-func reportJobLog(job JobMetadata, warnings []string, errors []string) error {
+// reportJobLog reports the job log using formatted warnings and errors along with the structured log entries and the tool runs
+func reportJobLog(job JobMetadata, requestMetadata JobLogRequestMetadata) error {
 	if job.Id == nil {
 		return fmt.Errorf("invalid job id")
 	}
 
 	reqUrl := fmt.Sprintf("%s/jobs/%s/log", api2Url, job.Id.String())
 
-	var requestMetadata = JobLogRequestMetadata{Warnings: warnings, Errors: errors}
-
 	b, err := json.Marshal(requestMetadata)
 	if err != nil {
 		return fmt.Errorf("failed to serialize json: %s", err)
@@ -96,20 +96,6 @@ func reportJobLog(job JobMetadata, warnings []string, errors []string) error {
 
 // uploadFile uploads the job results to the API for storage
 func uploadJobEntityFile(job JobMetadata, entityId *uuid.UUID, fileType string, fileMime string, path string, originalPath string, params map[string]string) error {
-	const chunkSize = 100 * 1024 * 1024 // 100MiB
-
-	// Validate job
-	if job.Id == nil || job.Id.IsNil() {
-		return fmt.Errorf("invalid job id")
-	}
-
-	if entityId == nil || entityId.IsNil() {
-		return fmt.Errorf("invalid job package id")
-	}
-
-	// Warning! For the package upload we don't set index and original-path to prevent duplicates, if these fields provided, we will get an error on DB index in future re-uploads of the package
-	reqUrl := fmt.Sprintf("%s/entities/%s/files/upload?type=%s&mime=%s&deployment=%s&platform=%s&original-path=%s", api2Url, entityId.String(), fileType, fileMime, job.Deployment, job.Platform, originalPath)
-
 	// Open file
 	file, err := os.Open(path)
 	if err != nil {
@@ -130,6 +116,27 @@ func uploadJobEntityFile(job JobMetadata, entityId *uuid.UUID, fileType string,
 		}
 	}(file)
 
+	return uploadJobEntityReader(job, entityId, fileType, fileMime, file, fi.Size(), fi.Name(), originalPath, params)
+}
+
+// uploadJobEntityReader uploads size bytes of the reader as the file of the entity, e.g. a section of a larger file
+func uploadJobEntityReader(job JobMetadata, entityId *uuid.UUID, fileType string, fileMime string, file io.Reader, size int64, name string, originalPath string, params map[string]string) error {
+	const chunkSize = 100 * 1024 * 1024 // 100MiB
+
+	var err error
+
+	// Validate job
+	if job.Id == nil || job.Id.IsNil() {
+		return fmt.Errorf("invalid job id")
+	}
+
+	if entityId == nil || entityId.IsNil() {
+		return fmt.Errorf("invalid job package id")
+	}
+
+	// Warning! For the package upload we don't set index and original-path to prevent duplicates, if these fields provided, we will get an error on DB index in future re-uploads of the package
+	reqUrl := fmt.Sprintf("%s/entities/%s/files/upload?type=%s&mime=%s&deployment=%s&platform=%s&original-path=%s", api2Url, entityId.String(), fileType, fileMime, job.Deployment, job.Platform, originalPath)
+
 	// Temporary buffer to get multipart form fields (header) and the boundary
 	multipartFormBuffer := &bytes.Buffer{}
 
@@ -140,7 +147,7 @@ func uploadJobEntityFile(job JobMetadata, entityId *uuid.UUID, fileType string,
 	}
 
 	// Add a file to the multipart form writer, the field name should be "file" as the API expects it
-	_, err = multipartFormWriter.CreateFormFile("file", fi.Name())
+	_, err = multipartFormWriter.CreateFormFile("file", name)
 	if err != nil {
 		return fmt.Errorf("failed to create a multipart form file: %v", err)
 	}
@@ -173,7 +180,7 @@ func uploadJobEntityFile(job JobMetadata, entityId *uuid.UUID, fileType string,
 	}
 
 	// Calculate the total content size including opening header size, uploaded file size and closing boundary length
-	multipartDataTotalSize := int64(multipartFormOpeningHeaderSize) + fi.Size() + int64(multipartFormClosingBoundarySize)
+	multipartDataTotalSize := int64(multipartFormOpeningHeaderSize) + size + int64(multipartFormClosingBoundarySize)
 
 	// Use a pipe to write request data
 	pipeReader, pipeWriter := io.Pipe()
@@ -257,6 +264,10 @@ func uploadJobEntityFile(job JobMetadata, entityId *uuid.UUID, fileType string,
 func fetchUnclaimedJob() (*JobMetadata, error) {
 	// Prepare an HTTP request
 	reqUrl := fmt.Sprintf("%s/jobs/unclaimed?platform=%s&type=%s&deployment=%s", api2Url, platforms, jobTypes, deployments)
+	// Jobs requesting an engine version which is not installed are left to the other runners
+	if versions := availableEngineVersions(); len(versions) > 0 {
+		reqUrl += "&engineVersions=" + url.QueryEscape(strings.Join(versions, ","))
+	}
 	req, err := http.NewRequest("GET", reqUrl, nil)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
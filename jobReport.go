@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultMetricsHistoryPath is the local file collecting the build metrics of all jobs for trend analysis
+const defaultMetricsHistoryPath = "job-metrics.jsonl"
+
+// JobMetricsRecord is a line of the local metrics history
+type JobMetricsRecord struct {
+	JobId         string          `json:"jobId"`
+	Type          string          `json:"type"`
+	Deployment    string          `json:"deployment"`
+	Platform      string          `json:"platform"`
+	Configuration string          `json:"configuration,omitempty"`
+	Time          time.Time       `json:"time"`
+	Runs          []*JobRunReport `json:"runs"`
+}
+
+// jobReport collects the results of the tool runs of a job and reports them to the API once when the job finishes
+type jobReport struct {
+	job JobMetadata
+
+	mu      sync.Mutex
+	request JobLogRequestMetadata
+}
+
+var (
+	activeJobReportMu sync.Mutex
+	activeJobReport   *jobReport // Report of the job being processed
+)
+
+// startJobReport makes a new report of the job the active one
+func startJobReport(job JobMetadata) *jobReport {
+	r := &jobReport{job: job}
+
+	activeJobReportMu.Lock()
+	activeJobReport = r
+	activeJobReportMu.Unlock()
+
+	return r
+}
+
+// currentJobReport returns the active report of the job, nil if there is none
+func currentJobReport(job JobMetadata) *jobReport {
+	activeJobReportMu.Lock()
+	r := activeJobReport
+	activeJobReportMu.Unlock()
+
+	if r == nil || job.Id == nil || r.job.Id == nil || *r.job.Id != *job.Id {
+		return nil
+	}
+
+	return r
+}
+
+// mergedEntries returns the entries of all the runs, identical entries of retried or repeated runs are counted once with the highest count
+func (r *jobReport) mergedEntries() []*LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return mergeLogEntries(r.request.Entries)
+}
+
+// mergeLogEntries counts identical entries once with the highest count, the entries are copied
+func mergeLogEntries(all []*LogEntry) []*LogEntry {
+	var entries []*LogEntry
+	index := map[string]*LogEntry{}
+	for _, e := range all {
+		if existing, ok := index[e.key()]; ok {
+			if e.Count > existing.Count {
+				existing.Count = e.Count
+			}
+			continue
+		}
+		merged := *e
+		index[e.key()] = &merged
+		entries = append(entries, &merged)
+	}
+
+	return entries
+}
+
+// uniqueMessages returns the messages without repeats keeping the order they first appeared in
+func uniqueMessages(all []string) []string {
+	var messages []string
+	seen := map[string]bool{}
+	for _, m := range all {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		messages = append(messages, m)
+	}
+
+	return messages
+}
+
+// setWarningBudget sets the quality gate result reported with the job log
+func (r *jobReport) setWarningBudget(result *WarningBudgetResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.request.WarningBudget = result
+}
+
+// warningBudgetExceeded tells whether the quality gate found the warning budget exceeded
+func (r *jobReport) warningBudgetExceeded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.request.WarningBudget != nil && r.request.WarningBudget.Exceeded
+}
+
+// setContentPolicy sets the package content scan result reported with the job log
+func (r *jobReport) setContentPolicy(report *ContentPolicyReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.request.ContentPolicy = report
+}
+
+// jobRunReporter returns the function adding the tool run to the active report of the job, runs are only logged if there is no report
+func jobRunReporter(job JobMetadata) func(run *JobRunReport, result UnrealAutomationToolResult) {
+	activeJobReportMu.Lock()
+	r := activeJobReport
+	activeJobReportMu.Unlock()
+
+	return func(run *JobRunReport, result UnrealAutomationToolResult) {
+		Logger.Infof("%s %s finished in %.0fs with exit code %d", run.Tool, run.Command, run.Duration, run.ExitCode)
+		if run.Metrics != nil {
+			for _, p := range run.Metrics.Phases {
+				Logger.Infof("%s phase took %.0fs", p.Name, p.Duration)
+			}
+		}
+
+		if r == nil || job.Id == nil || r.job.Id == nil || *r.job.Id != *job.Id {
+			return
+		}
+
+		// Uploaded before the run is reported so the report refers to the artifacts
+		uploadCrashArtifacts(job, run.Crashes)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.request.Warnings = append(r.request.Warnings, result.Warnings...)
+		r.request.Errors = append(r.request.Errors, result.Errors...)
+		r.request.Entries = append(r.request.Entries, result.Entries...)
+		r.request.Runs = append(r.request.Runs, run)
+	}
+}
+
+// Close reports the collected runs to the API and appends their metrics to the local history
+func (r *jobReport) Close() {
+	if r == nil {
+		return
+	}
+
+	activeJobReportMu.Lock()
+	if activeJobReport == r {
+		activeJobReport = nil
+	}
+	activeJobReportMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Rejected packages are reported to the creator without any runs
+	if len(r.request.Runs) == 0 && r.request.ContentPolicy == nil {
+		return
+	}
+
+	// Retried runs repeat the messages of the failed attempts, the runs themselves are kept per attempt
+	request := r.request
+	request.Warnings = uniqueMessages(r.request.Warnings)
+	request.Errors = uniqueMessages(r.request.Errors)
+	request.Entries = mergeLogEntries(r.request.Entries)
+
+	if err := reportJobLog(r.job, request); err != nil {
+		Logger.Errorf("failed to report job log: %v", err)
+	}
+
+	if len(r.request.Runs) == 0 {
+		return
+	}
+
+	if err := appendJobMetricsHistory(r.job, r.request.Runs); err != nil {
+		Logger.Errorf("failed to store job metrics: %v", err)
+	}
+}
+
+// appendJobMetricsHistory appends the job runs as a JSON line to the local metrics history
+func appendJobMetricsHistory(job JobMetadata, runs []*JobRunReport) (err error) {
+	path := config.MetricsPath
+	if path == "" {
+		path = defaultMetricsHistoryPath
+	}
+
+	record := JobMetricsRecord{
+		Type:          job.Type,
+		Deployment:    job.Deployment,
+		Platform:      job.Platform,
+		Configuration: job.Configuration,
+		Time:          time.Now().UTC(),
+		Runs:          runs,
+	}
+	if job.Id != nil {
+		record.JobId = job.Id.String()
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to serialize json: %v", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create a directory %s: %v", dir, err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+
+	defer func(f *os.File) {
+		if err1 := f.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", path, err1)
+		}
+	}(f)
+
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// knownErrorsFileName is the versioned knowledge base of known errors looked up like the rule files
+const knownErrorsFileName = ".veverse-automation-known-errors.json"
+
+// KnownErrors is the knowledge base mapping log entry patterns to explanations and fix hints
+type KnownErrors struct {
+	Version int               `json:"version"` // Incremented on every change of the rules, reported with the hints
+	Rules   []*KnownErrorRule `json:"rules"`
+}
+
+// KnownErrorRule explains the log entries matching the pattern, the first matching rule wins
+type KnownErrorRule struct {
+	Id          string   `json:"id"`
+	Pattern     string   `json:"pattern"`              // Regular expression matched against the formatted log entry
+	Severities  []string `json:"severities,omitempty"` // Entry severities to match, any if empty
+	Kinds       []string `json:"kinds,omitempty"`      // Entry kinds to match, any if empty
+	Categories  []string `json:"categories,omitempty"` // Entry log categories to match, any if empty
+	Explanation string   `json:"explanation"`          // What the error means in the words of a content creator
+	Hint        string   `json:"hint"`                 // How to fix it
+
+	regexp *regexp.Regexp
+}
+
+// KnownErrorHint is the explanation attached to a log entry matching a known error rule
+type KnownErrorHint struct {
+	Id          string `json:"id"`
+	Version     int    `json:"version"` // Knowledge base version
+	Explanation string `json:"explanation"`
+	Hint        string `json:"hint"`
+}
+
+var (
+	knownErrorsOnce sync.Once
+	knownErrors     *KnownErrors // Knowledge base used by the log parser, nil if not found or invalid
+)
+
+// defaultKnownErrors loads the knowledge base once, log entries are not explained if it can not be loaded
+func defaultKnownErrors() *KnownErrors {
+	knownErrorsOnce.Do(func() {
+		kb, err := loadKnownErrors(knownErrorsFileName)
+		if err != nil {
+			Logger.Errorf("failed to load known errors: %v", err)
+			return
+		}
+		knownErrors = kb
+	})
+
+	return knownErrors
+}
+
+// loadKnownErrors reads the knowledge base from the project directory, falling back to the working directory, returns nil if the file does not exist
+func loadKnownErrors(name string) (*KnownErrors, error) {
+	var data []byte
+	var err error
+	for _, p := range []string{filepath.Join(projectDir, name), name} {
+		data, err = os.ReadFile(p)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read from %s file: %v", p, err)
+		}
+	}
+
+	// Not logged as the logs command writes reports to stdout
+	if err != nil {
+		return nil, nil
+	}
+
+	return parseKnownErrors(data)
+}
+
+// parseKnownErrors parses the knowledge base and compiles the rule patterns
+func parseKnownErrors(data []byte) (*KnownErrors, error) {
+	var kb KnownErrors
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse known errors: %v", err)
+	}
+
+	for i, r := range kb.Rules {
+		if r.Id == "" {
+			return nil, fmt.Errorf("known error rule %d has no id", i)
+		}
+
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid known error rule %s pattern: %v", r.Id, err)
+		}
+		r.regexp = re
+	}
+
+	return &kb, nil
+}
+
+// Explain returns the hint of the first rule matching the entry, nil if the entry is not a known error
+func (kb *KnownErrors) Explain(entry *LogEntry) *KnownErrorHint {
+	if kb == nil {
+		return nil
+	}
+
+	s := entry.String()
+	for _, r := range kb.Rules {
+		if !matchesAny(r.Severities, entry.Severity) || !matchesAny(r.Kinds, entry.Kind) || !matchesAny(r.Categories, entry.Category) {
+			continue
+		}
+
+		if r.regexp.MatchString(s) {
+			return &KnownErrorHint{Id: r.Id, Version: kb.Version, Explanation: r.Explanation, Hint: r.Hint}
+		}
+	}
+
+	return nil
+}
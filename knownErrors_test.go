@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestDefaultKnownErrors(t *testing.T) {
+	kb := defaultKnownErrors()
+	if kb == nil {
+		t.Fatalf("failed to load %s", knownErrorsFileName)
+	}
+
+	tests := []struct {
+		line string
+		id   string // Empty if the entry is not a known error
+	}{
+		{"LogLinker: Warning: [AssetLog] D:\\Build\\Metaverse\\Plugins\\VePackage\\Content\\Maps\\Lobby.umap: Asset file version is too new. Engine Version: 1009 Package Version: 1010", "asset-newer-engine"},
+		{"LogLinker: Error: Package /Game/VePackage/Meshes/SM_Chair is too old. Min Version: 214  Package Version: 200", "asset-older-engine"},
+		{"ERROR: Plugin 'VePackage' is designed for build 5.2.0, not compatible with the current engine version", "engine-version-mismatch"},
+		{"LogInit: Error: The following modules are missing or built with a different engine version: VePackage", "unsupported-plugin-modules"},
+		{"LogCook: Warning: [AssetLog] D:\\Build\\Metaverse\\Plugins\\VePackage\\Content\\Maps\\Lobby.umap: Failed import for MaterialInstanceConstant /Game/VePackage/Materials/MI_Floor", "missing-referenced-asset"},
+		{"LogLoad: Error: Failed to load map /Game/VePackage/Maps/Lobby", "missing-map"},
+		// The map rule only applies to UAT and engine log entries
+		{"LogCook: Warning: Failed to load map /Game/VePackage/Maps/Lobby", ""},
+		{"LogNet: Warning: Network failure: ConnectionLost", ""},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		p := NewUnrealLogParser()
+		p.ParseLine(tt.line)
+
+		entries := p.Entries()
+		if len(entries) != 1 {
+			t.Fatalf("ParseLine(%q) returned %d entries, want 1", tt.line, len(entries))
+		}
+
+		id := ""
+		if entries[0].KnownError != nil {
+			id = entries[0].KnownError.Id
+			if entries[0].KnownError.Version != kb.Version || entries[0].KnownError.Explanation == "" {
+				t.Errorf("known error of %q = %+v, want the version and explanation of the rule", tt.line, entries[0].KnownError)
+			}
+		}
+		if id != tt.id {
+			t.Errorf("known error of %q = %q, want %q", tt.line, id, tt.id)
+		}
+		covered[tt.id] = true
+	}
+
+	for _, r := range kb.Rules {
+		if !covered[r.Id] {
+			t.Errorf("no test of the known error rule %s", r.Id)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogArchiveDir  = "job-logs"
+	defaultLogArchiveKeep = 20
+	logArchiveFileName    = "logs.zip"
+)
+
+// jobLogArchive collects the logs of a job in a per-job directory, compresses and uploads them when the job finishes
+type jobLogArchive struct {
+	job     JobMetadata
+	dir     string    // Per-job directory the tool logs are written to
+	started time.Time // Logs modified before the job started belong to other jobs
+
+	mu   sync.Mutex
+	runs int // Number of AutomationTool runs, each run writes its own log
+}
+
+var (
+	activeJobLogArchiveMu sync.Mutex
+	activeJobLogArchive   *jobLogArchive // Archive of the job being processed
+)
+
+// startJobLogArchive creates the per-job log directory and makes it the active archive, returns nil if archiving is disabled
+func startJobLogArchive(job JobMetadata) *jobLogArchive {
+	if config.LogArchive.Disabled || job.Id == nil {
+		return nil
+	}
+
+	dir := filepath.Join(logArchiveRoot(), fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102-150405"), job.Id.String()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		Logger.Errorf("failed to create job log directory %s: %v", dir, err)
+		return nil
+	}
+
+	a := &jobLogArchive{job: job, dir: dir, started: time.Now()}
+
+	activeJobLogArchiveMu.Lock()
+	activeJobLogArchive = a
+	activeJobLogArchiveMu.Unlock()
+
+	return a
+}
+
+// logArchiveRoot returns the directory keeping the per-job log directories
+func logArchiveRoot() string {
+	if config.LogArchive.Dir != "" {
+		return config.LogArchive.Dir
+	}
+
+	return defaultLogArchiveDir
+}
+
+// jobToolLogPath returns the path of the log file for the next run of the tool, uat.log in the working directory if the job has no archive
+func jobToolLogPath(job JobMetadata, tool string) string {
+	activeJobLogArchiveMu.Lock()
+	a := activeJobLogArchive
+	activeJobLogArchiveMu.Unlock()
+
+	if a == nil || job.Id == nil || a.job.Id == nil || *a.job.Id != *job.Id {
+		return tool + ".log"
+	}
+
+	a.mu.Lock()
+	a.runs++
+	n := a.runs
+	a.mu.Unlock()
+
+	return filepath.Join(a.dir, fmt.Sprintf("%s-%02d.log", tool, n))
+}
+
+// Close copies the engine and project logs written during the job into the job directory, uploads the compressed logs and removes the oldest job directories
+func (a *jobLogArchive) Close() {
+	if a == nil {
+		return
+	}
+
+	activeJobLogArchiveMu.Lock()
+	if activeJobLogArchive == a {
+		activeJobLogArchive = nil
+	}
+	activeJobLogArchiveMu.Unlock()
+
+	for name, dir := range jobLogSourceDirs(a.job) {
+		if err := copyRecentLogs(dir, filepath.Join(a.dir, name), a.started); err != nil {
+			Logger.Warningf("failed to copy logs from %s: %v", dir, err)
+		}
+	}
+
+	files := map[string]string{}
+	err := filepath.WalkDir(a.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == logArchiveFileName {
+			return nil
+		}
+		rel, err := filepath.Rel(a.dir, path)
+		if err != nil {
+			return err
+		}
+		files[path] = filepath.ToSlash(rel)
+		return nil
+	})
+	if err != nil {
+		Logger.Errorf("failed to list job logs: %v", err)
+	}
+
+	if len(files) > 0 {
+		zipPath := filepath.Join(a.dir, logArchiveFileName)
+		if err := zipFiles(zipPath, files); err != nil {
+			Logger.Errorf("failed to compress job logs: %v", err)
+		} else if err := uploadJobLogArchiveFile(a.job, zipPath, nil); err != nil {
+			Logger.Errorf("failed to upload job logs: %v", err)
+		}
+	}
+
+	if err := pruneJobLogArchives(logArchiveRoot(), a.dir); err != nil {
+		Logger.Warningf("failed to remove old job logs: %v", err)
+	}
+}
+
+// jobLogSourceDirs returns the log directories of the engine and the project written by the job tools, keyed by the archive sub-directory
+func jobLogSourceDirs(job JobMetadata) map[string]string {
+	dirs := map[string]string{}
+
+	if projectDir != "" {
+		dirs["Project"] = filepath.Join(projectDir, "Saved", "Logs")
+		if job.Type == "Package" && job.Package != nil && job.Package.Name != "" {
+			dirs["Plugin"] = filepath.Join(projectDir, "Plugins", job.Package.Name, "Saved", "Logs")
+		}
+	}
+
+	if engineDir := jobEngineDir(); engineDir != "" {
+		dirs["AutomationTool"] = filepath.Join(engineDir, "Programs", "AutomationTool", "Saved", "Logs")
+		dirs["UnrealBuildTool"] = filepath.Join(engineDir, "Programs", "UnrealBuildTool")
+	}
+
+	return dirs
+}
+
+// jobEngineDir returns the Engine directory of the engine used by the job, empty if it is unknown
+func jobEngineDir() string {
+	if jobEngine != nil && jobEngine.Path != "" {
+		return filepath.Join(jobEngine.Path, "Engine")
+	}
+
+	// UAT is under the Engine directory, e.g. Engine/Build/BatchFiles/RunUAT or Engine/Binaries/DotNET/AutomationTool/AutomationTool
+	for dir := filepath.Dir(uatPath); uatPath != ""; {
+		if strings.EqualFold(filepath.Base(dir), "Engine") {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return ""
+}
+
+// copyRecentLogs copies .log and .txt files of the directory modified since the time, missing directories are skipped
+func copyRecentLogs(src string, dst string, since time.Time) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".log" && ext != ".txt" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().Before(since) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dst, rel)), 0755); err != nil {
+			return err
+		}
+
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+// pruneJobLogArchives keeps the configured number of the newest job log directories, the current one is always kept
+func pruneJobLogArchives(root string, current string) error {
+	keep := defaultLogArchiveKeep
+	if config.LogArchive.Keep != nil {
+		keep = *config.LogArchive.Keep
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && filepath.Join(root, e.Name()) != current {
+			dirs = append(dirs, e.Name())
+		}
+	}
+
+	// Directory names start with the job start time so the oldest come first
+	sort.Strings(dirs)
+
+	// The current directory counts towards the kept ones
+	for len(dirs) > 0 && len(dirs) >= keep {
+		if err := os.RemoveAll(filepath.Join(root, dirs[0])); err != nil {
+			return err
+		}
+		dirs = dirs[1:]
+	}
+
+	return nil
+}
+
+// uploadJobLogArchiveFile uploads the compressed job logs to the API for storage
+func uploadJobLogArchiveFile(job JobMetadata, path string, params map[string]string) error {
+	Logger.Infof("uploading job log archive %s", path)
+
+	var (
+		fileType = "job-log-archive"
+		fileMime = "application/zip"
+	)
+
+	return uploadJobEntityFile(job, job.Id, fileType, fileMime, path, "", params)
+}
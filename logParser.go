@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	LogSeverityError   = "Error"
+	LogSeverityWarning = "Warning"
+)
+
+const (
+	LogKindCompiler = "Compiler" // UBT compiler and linker diagnostics
+	LogKindCook     = "Cook"     // Cook diagnostics referring to an asset
+	LogKindLog      = "Log"      // Engine log category diagnostics
+	LogKindEnsure   = "Ensure"   // Failed ensure with a callstack
+	LogKindAssert   = "Assert"   // Failed assertion or fatal error with a callstack
+	LogKindCrash    = "Crash"    // Unhandled exception or signal with a callstack
+	LogKindUAT      = "UAT"      // AutomationTool own errors and exit summary
+)
+
+// LogEntry is a single diagnostic extracted from the Unreal log, identical diagnostics are merged and counted
+type LogEntry struct {
+	Severity   string          `json:"severity"`
+	Kind       string          `json:"kind"`
+	Category   string          `json:"category,omitempty"` // Log category without the "Log" prefix, e.g. Cook
+	Message    string          `json:"message"`
+	File       string          `json:"file,omitempty"`
+	Line       int             `json:"line,omitempty"`
+	Column     int             `json:"column,omitempty"`
+	Code       string          `json:"code,omitempty"`  // Compiler diagnostic code, e.g. C2065 or -Wunused-variable
+	Asset      string          `json:"asset,omitempty"` // Asset the cook diagnostic refers to
+	Callstack  []string        `json:"callstack,omitempty"`
+	Count      int             `json:"count"`                // Number of occurrences in the log
+	KnownError *KnownErrorHint `json:"knownError,omitempty"` // Explanation and fix hint if the entry is a known error
+}
+
+// String formats the entry as a single human-readable line
+func (e *LogEntry) String() string {
+	var b strings.Builder
+
+	switch {
+	case e.File != "":
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			if e.Column > 0 {
+				b.WriteString(fmt.Sprintf("(%d,%d)", e.Line, e.Column))
+			} else {
+				b.WriteString(fmt.Sprintf("(%d)", e.Line))
+			}
+		}
+		b.WriteString(": ")
+	case e.Category != "":
+		b.WriteString("Log" + e.Category + ": ")
+	}
+
+	b.WriteString(e.Severity)
+	if e.Code != "" {
+		b.WriteString(" " + e.Code)
+	}
+	b.WriteString(": " + e.Message)
+
+	if e.Asset != "" && !strings.Contains(e.Message, e.Asset) {
+		b.WriteString(" (" + e.Asset + ")")
+	}
+
+	if e.Count > 1 {
+		b.WriteString(fmt.Sprintf(" [x%d]", e.Count))
+	}
+
+	return b.String()
+}
+
+// Describe formats the entry with the known error explanation and fix hint if any
+func (e *LogEntry) Describe() string {
+	s := e.String()
+	if e.KnownError != nil {
+		s += "\n" + e.KnownError.Explanation
+		if e.KnownError.Hint != "" {
+			s += "\nHint: " + e.KnownError.Hint
+		}
+	}
+
+	return s
+}
+
+// key identifies identical diagnostics
+func (e *LogEntry) key() string {
+	return strings.Join([]string{e.Severity, e.Kind, e.Category, e.File, strconv.Itoa(e.Line), strconv.Itoa(e.Column), e.Code, e.Asset, e.Message}, "\x00")
+}
+
+var (
+	// Optional engine log prefix, e.g. "[2023.01.10-12.00.00:000][  0]"
+	logTimestampPrefix = `^\s*(?:\[[^\]]*\]\[\s*\d+\])?\s*`
+
+	// MSVC diagnostic, e.g. "X:/Project/Source/A.cpp(12): error C2065: 'x': undeclared identifier"
+	msvcDiagnosticRegexp = regexp.MustCompile(`^\s*(.+?)\((\d+)(?:,(\d+))?\)\s*:\s*(fatal error|error|warning)\s+([A-Z]+\d+)\s*:\s*(.*)$`)
+
+	// Clang and GCC diagnostic, e.g. "/Project/Source/A.cpp:12:5: error: use of undeclared identifier 'x' [-Wsomething]"
+	clangDiagnosticRegexp = regexp.MustCompile(`^\s*(.+?):(\d+):(\d+):\s*(fatal error|error|warning):\s*(.*?)(?:\s*\[(-W[^\]]+)\])?$`)
+
+	// Engine log category diagnostic, e.g. "LogCook: Warning: ..."
+	logCategoryRegexp = regexp.MustCompile(logTimestampPrefix + `Log(\w+):\s*(Error|Warning|Fatal):\s*(.*)$`)
+
+	// Asset reference in the cook diagnostic, e.g. "[AssetLog] X:/Project/Content/A.uasset: ..."
+	assetLogRegexp = regexp.MustCompile(`^\[AssetLog\]\s+(.+?\.(?:uasset|umap)):\s*(.*)$`)
+
+	// Package path in the cook diagnostic, e.g. "/Game/Maps/Main.Main"
+	packagePathRegexp = regexp.MustCompile(`(?:^|[\s'"(])(/(?:Game|Engine|Script|[A-Z]\w*)/[\w/\.\-]+)`)
+
+	// Callstack frame, e.g. "[Callstack] 0x00007ffd UnrealEditor-Core.dll!FDebug::CheckVerifyFailedImpl() [X:/Core/AssertionMacros.cpp:123]"
+	callstackRegexp = regexp.MustCompile(`^\[Callstack\]\s*(.*)$`)
+
+	// Ensure, assertion and fatal error messages
+	ensureRegexp = regexp.MustCompile(`^Ensure condition failed:\s*(.*)$`)
+	assertRegexp = regexp.MustCompile(`^(?:appError called:\s*)?(Assertion failed:\s*.*|Fatal error:\s*.*)$`)
+
+	// Unhandled exceptions and signals, e.g. "Unhandled Exception: EXCEPTION_ACCESS_VIOLATION reading address 0x00000000" or "Caught signal 11 Segmentation fault"
+	crashRegexp  = regexp.MustCompile(`^((?:Unhandled Exception|Exception thrown):\s*.*|Caught signal\s*\d+.*|Signal\s*\d+\s+caught.*)$`)
+	signalRegexp = regexp.MustCompile(`^\s*(Caught signal\s*\d+.*|Signal\s*\d+\s+caught.*|CommonUnixCrashHandler:\s*Signal=\d+.*)$`)
+
+	// Source location in ensure and assertion messages, e.g. "[File:X:/Source/A.cpp] [Line: 12]"
+	assertLocationRegexp = regexp.MustCompile(`\[File:\s*([^\]]+)\]\s*\[Line:\s*(\d+)\]`)
+
+	// AutomationTool own error lines and the exit summary
+	uatErrorRegexp    = regexp.MustCompile(`^\s*(ERROR|WARNING):\s*(.*)$`)
+	uatExitCodeRegexp = regexp.MustCompile(`^AutomationTool exiting with ExitCode=(\d+)\s*\(([^)]*)\)`)
+)
+
+// UnrealLogParser extracts structured diagnostics from UAT, UBT and editor logs line by line
+type UnrealLogParser struct {
+	entries     []*LogEntry
+	index       map[string]*LogEntry
+	current     *LogEntry    // Ensure or assert entry collecting the following callstack lines
+	knownErrors *KnownErrors // Knowledge base explaining the entries, nil if not available
+	ExitCode    *int         // Exit code reported by AutomationTool, nil if not found
+}
+
+// NewUnrealLogParser creates an empty log parser explaining the entries with the known errors knowledge base
+func NewUnrealLogParser() *UnrealLogParser {
+	return &UnrealLogParser{index: map[string]*LogEntry{}, knownErrors: defaultKnownErrors()}
+}
+
+// ParseLine parses a single log line
+func (p *UnrealLogParser) ParseLine(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if m := logCategoryRegexp.FindStringSubmatch(line); m != nil {
+		p.parseCategoryLine(m[1], m[2], m[3])
+		return
+	}
+
+	// Any other line ends the callstack
+	p.current = nil
+
+	if m := signalRegexp.FindStringSubmatch(line); m != nil {
+		p.current = p.add(&LogEntry{Severity: LogSeverityError, Kind: LogKindCrash, Message: strings.TrimSpace(m[1])})
+		return
+	}
+
+	if m := msvcDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+		p.add(&LogEntry{
+			Severity: compilerSeverity(m[4]),
+			Kind:     LogKindCompiler,
+			File:     logFilePath(m[1]),
+			Line:     atoi(m[2]),
+			Column:   atoi(m[3]),
+			Code:     m[5],
+			Message:  m[6],
+		})
+		return
+	}
+
+	if m := clangDiagnosticRegexp.FindStringSubmatch(line); m != nil {
+		p.add(&LogEntry{
+			Severity: compilerSeverity(m[4]),
+			Kind:     LogKindCompiler,
+			File:     logFilePath(m[1]),
+			Line:     atoi(m[2]),
+			Column:   atoi(m[3]),
+			Code:     m[6],
+			Message:  m[5],
+		})
+		return
+	}
+
+	if m := uatExitCodeRegexp.FindStringSubmatch(line); m != nil {
+		code := atoi(m[1])
+		p.ExitCode = &code
+		if code != 0 {
+			p.add(&LogEntry{
+				Severity: LogSeverityError,
+				Kind:     LogKindUAT,
+				Code:     m[2],
+				Message:  uatExitCodeMessage(code),
+			})
+		}
+		return
+	}
+
+	if m := uatErrorRegexp.FindStringSubmatch(line); m != nil {
+		severity := LogSeverityError
+		if m[1] == "WARNING" {
+			severity = LogSeverityWarning
+		}
+		p.add(&LogEntry{Severity: severity, Kind: LogKindUAT, Message: m[2]})
+		return
+	}
+}
+
+// parseCategoryLine parses the "Log<Category>: <Severity>: <Message>" line
+func (p *UnrealLogParser) parseCategoryLine(category string, severity string, message string) {
+	message = strings.TrimSpace(message)
+
+	if m := callstackRegexp.FindStringSubmatch(message); m != nil {
+		if p.current != nil {
+			if p.current.Count == 1 {
+				p.current.Callstack = append(p.current.Callstack, m[1])
+			}
+			return
+		}
+		// Callstack without a preceding ensure or assertion, e.g. of a crash, start a new entry on the first frame
+		p.current = p.add(&LogEntry{Severity: LogSeverityError, Kind: LogKindCrash, Category: category, Message: "Unhandled exception"})
+		if p.current.Count == 1 {
+			p.current.Callstack = append(p.current.Callstack, m[1])
+		}
+		return
+	}
+
+	if severity == "Fatal" {
+		severity = LogSeverityError
+	}
+
+	if m := ensureRegexp.FindStringSubmatch(message); m != nil {
+		entry := &LogEntry{Severity: severity, Kind: LogKindEnsure, Category: category, Message: message}
+		setAssertLocation(entry, m[1])
+		p.current = p.add(entry)
+		return
+	}
+
+	if m := assertRegexp.FindStringSubmatch(message); m != nil {
+		entry := &LogEntry{Severity: LogSeverityError, Kind: LogKindAssert, Category: category, Message: m[1]}
+		setAssertLocation(entry, m[1])
+		p.current = p.add(entry)
+		return
+	}
+
+	if m := crashRegexp.FindStringSubmatch(message); m != nil {
+		p.current = p.add(&LogEntry{Severity: LogSeverityError, Kind: LogKindCrash, Category: category, Message: m[1]})
+		return
+	}
+
+	p.current = nil
+
+	entry := &LogEntry{Severity: severity, Kind: LogKindLog, Category: category, Message: message}
+
+	if m := assetLogRegexp.FindStringSubmatch(message); m != nil {
+		entry.Kind = LogKindCook
+		entry.Asset = logFilePath(m[1])
+		entry.Message = m[2]
+	} else if m := packagePathRegexp.FindStringSubmatch(message); m != nil && isCookCategory(category) {
+		entry.Kind = LogKindCook
+		entry.Asset = strings.TrimRight(m[1], ".")
+	}
+
+	p.add(entry)
+}
+
+// add merges the entry with an identical one if already seen and returns the stored entry
+func (p *UnrealLogParser) add(entry *LogEntry) *LogEntry {
+	key := entry.key()
+	if existing, ok := p.index[key]; ok {
+		existing.Count++
+		return existing
+	}
+
+	entry.Count = 1
+	entry.KnownError = p.knownErrors.Explain(entry)
+	p.index[key] = entry
+	p.entries = append(p.entries, entry)
+
+	return entry
+}
+
+// Entries returns the parsed entries in order of the first occurrence
+func (p *UnrealLogParser) Entries() []*LogEntry {
+	return p.entries
+}
+
+// Messages returns formatted warnings and errors along with the known error explanations
+func (p *UnrealLogParser) Messages() (warnings []string, errors []string) {
+	for _, e := range p.entries {
+		if e.Severity == LogSeverityError {
+			errors = append(errors, e.Describe())
+		} else {
+			warnings = append(warnings, e.Describe())
+		}
+	}
+
+	return
+}
+
+// isCookCategory reports whether diagnostics of the log category are produced by cooking assets
+func isCookCategory(category string) bool {
+	switch category {
+	case "Cook", "SavePackage", "Linker", "LinkerLoad", "UObjectGlobals", "Blueprint", "Material", "Texture", "StaticMesh", "Shaders", "ShaderCompilers", "AssetRegistry", "Package", "PackageName", "StreamableManager", "DerivedDataCache":
+		return true
+	}
+
+	return false
+}
+
+// setAssertLocation sets the source location of an ensure or assertion entry if present in the message
+func setAssertLocation(entry *LogEntry, message string) {
+	if m := assertLocationRegexp.FindStringSubmatch(message); m != nil {
+		entry.File = logFilePath(m[1])
+		entry.Line = atoi(m[2])
+	}
+}
+
+// logFilePath converts the file path found in the log to forward slashes so Windows and Unix paths compare equal
+func logFilePath(file string) string {
+	return strings.ReplaceAll(strings.TrimSpace(file), "\\", "/")
+}
+
+// uatExitCodeMessage returns the message of the entry reporting the non-zero AutomationTool exit code
+func uatExitCodeMessage(code int) string {
+	return fmt.Sprintf("AutomationTool exited with code %d", code)
+}
+
+// compilerSeverity maps the compiler diagnostic severity to the log severity
+func compilerSeverity(s string) string {
+	if s == "warning" {
+		return LogSeverityWarning
+	}
+
+	return LogSeverityError
+}
+
+// atoi converts an optional number, returns zero if empty or invalid
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnrealLogParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		entries  []LogEntry
+		exitCode int // -1 if AutomationTool did not report an exit code
+	}{
+		{
+			name: "msvc",
+			lines: []string{
+				`D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp(42): error C2065: 'Foo': undeclared identifier`,
+				`  D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp(10,5): warning C4996: 'FVector::Size': was declared deprecated in D:\Engine\Source\Runtime\Core\Public\Math\Vector.h`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindCompiler, File: "D:/Build/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 42, Code: "C2065", Message: "'Foo': undeclared identifier", Count: 1},
+				{Severity: LogSeverityWarning, Kind: LogKindCompiler, File: "D:/Build/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 10, Column: 5, Code: "C4996", Message: `'FVector::Size': was declared deprecated in D:\Engine\Source\Runtime\Core\Public\Math\Vector.h`, Count: 1},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "clang",
+			lines: []string{
+				`/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp:12:9: warning: unused variable 'Count' [-Wunused-variable]`,
+				`/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp:30:1: error: expected ';' after class`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityWarning, Kind: LogKindCompiler, File: "/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 12, Column: 9, Code: "-Wunused-variable", Message: "unused variable 'Count'", Count: 1},
+				{Severity: LogSeverityError, Kind: LogKindCompiler, File: "/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 30, Column: 1, Message: "expected ';' after class", Count: 1},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "categories",
+			lines: []string{
+				`[2023.01.10-12.00.00:000][  0]LogInit: Display: Running engine for game: Metaverse`,
+				`[2023.01.10-12.00.01:000][  0]LogCook: Warning: [AssetLog] D:\Build\Metaverse\Content\Maps\Main.umap: Failed import for MaterialInstanceConstant /Game/Materials/MI_Floor`,
+				`LogLinker: Warning: Unable to load package /Game/Maps/Old.Old.`,
+				`LogNet: Error: UNetDriver::TickDispatch: Very long time between ticks`,
+				`LogPakFile: Fatal: Failed to open C:\Temp\Metaverse.pak`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityWarning, Kind: LogKindCook, Category: "Cook", Asset: "D:/Build/Metaverse/Content/Maps/Main.umap", Message: "Failed import for MaterialInstanceConstant /Game/Materials/MI_Floor", Count: 1},
+				{Severity: LogSeverityWarning, Kind: LogKindCook, Category: "Linker", Asset: "/Game/Maps/Old.Old", Message: "Unable to load package /Game/Maps/Old.Old.", Count: 1},
+				{Severity: LogSeverityError, Kind: LogKindLog, Category: "Net", Message: "UNetDriver::TickDispatch: Very long time between ticks", Count: 1},
+				{Severity: LogSeverityError, Kind: LogKindLog, Category: "PakFile", Message: `Failed to open C:\Temp\Metaverse.pak`, Count: 1},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "callstack",
+			lines: []string{
+				`LogWindows: Error: [Callstack] 0x00007ffb3c1a2b3c UnrealEditor-Core.dll!FGenericPlatformMisc::RaiseException() [D:\Engine\Source\Runtime\Core\Private\GenericPlatform\GenericPlatformMisc.cpp:430]`,
+				`LogWindows: Error: [Callstack] 0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+				`LogExit: Display: Exiting.`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindCrash, Category: "Windows", Message: "Unhandled exception", Count: 1, Callstack: []string{
+					`0x00007ffb3c1a2b3c UnrealEditor-Core.dll!FGenericPlatformMisc::RaiseException() [D:\Engine\Source\Runtime\Core\Private\GenericPlatform\GenericPlatformMisc.cpp:430]`,
+					`0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+				}},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "ensure",
+			lines: []string{
+				`[2023.01.10-12.00.00:000][ 12]LogOutputDevice: Error: Ensure condition failed: Component != nullptr [File:D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp] [Line: 87]`,
+				`[2023.01.10-12.00.00:000][ 12]LogOutputDevice: Error: [Callstack] 0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::BeginPlay() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+				`[2023.01.10-12.00.00:000][ 12]LogOutputDevice: Error: [Callstack] 0x00007ffb3c1a2d00 UnrealEditor-Engine.dll!AActor::DispatchBeginPlay() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:3960]`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindEnsure, Category: "OutputDevice", File: "D:/Build/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 87, Message: `Ensure condition failed: Component != nullptr [File:D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp] [Line: 87]`, Count: 1, Callstack: []string{
+					`0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::BeginPlay() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+					`0x00007ffb3c1a2d00 UnrealEditor-Engine.dll!AActor::DispatchBeginPlay() [D:\Engine\Source\Runtime\Engine\Private\Actor.cpp:3960]`,
+				}},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "assert",
+			lines: []string{
+				`LogWindows: Error: appError called: Assertion failed: IsValid(World) [File:D:\Build\Metaverse\Source\Metaverse\Private\VeGameMode.cpp] [Line: 120]`,
+				`LogWindows: Error: [Callstack] 0x00007ffb3c1a2e00 UnrealEditor-Metaverse.dll!AVeGameMode::InitGame() [D:\Build\Metaverse\Source\Metaverse\Private\VeGameMode.cpp:120]`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindAssert, Category: "Windows", File: "D:/Build/Metaverse/Source/Metaverse/Private/VeGameMode.cpp", Line: 120, Message: `Assertion failed: IsValid(World) [File:D:\Build\Metaverse\Source\Metaverse\Private\VeGameMode.cpp] [Line: 120]`, Count: 1, Callstack: []string{
+					`0x00007ffb3c1a2e00 UnrealEditor-Metaverse.dll!AVeGameMode::InitGame() [D:\Build\Metaverse\Source\Metaverse\Private\VeGameMode.cpp:120]`,
+				}},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "crash",
+			lines: []string{
+				`LogWindows: Error: Unhandled Exception: EXCEPTION_ACCESS_VIOLATION reading address 0x0000000000000000`,
+				`LogWindows: Error: [Callstack] 0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindCrash, Category: "Windows", Message: "Unhandled Exception: EXCEPTION_ACCESS_VIOLATION reading address 0x0000000000000000", Count: 1, Callstack: []string{
+					`0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::Tick() [D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp:87]`,
+				}},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "signal",
+			lines: []string{
+				`Caught signal 11 Segmentation fault`,
+				`LogCore: Error: [Callstack] 0x00007f2a1c2b3d4e libUnrealServer-Metaverse.so!AVeActor::Tick(float) [/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp:87]`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindCrash, Message: "Caught signal 11 Segmentation fault", Count: 1, Callstack: []string{
+					`0x00007f2a1c2b3d4e libUnrealServer-Metaverse.so!AVeActor::Tick(float) [/home/builder/Metaverse/Source/Metaverse/Private/VeActor.cpp:87]`,
+				}},
+			},
+			exitCode: -1,
+		},
+		{
+			name: "uat exit code",
+			lines: []string{
+				`ERROR: Cook failed.`,
+				`WARNING: Unable to find the symbol store`,
+				`AutomationTool exiting with ExitCode=25 (Error_UnknownCookFailure)`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityError, Kind: LogKindUAT, Message: "Cook failed.", Count: 1},
+				{Severity: LogSeverityWarning, Kind: LogKindUAT, Message: "Unable to find the symbol store", Count: 1},
+				{Severity: LogSeverityError, Kind: LogKindUAT, Code: "Error_UnknownCookFailure", Message: "AutomationTool exited with code 25", Count: 1},
+			},
+			exitCode: 25,
+		},
+		{
+			name: "uat success",
+			lines: []string{
+				`BUILD SUCCESSFUL`,
+				`AutomationTool exiting with ExitCode=0 (Success)`,
+			},
+			exitCode: 0,
+		},
+		{
+			name: "dedupe",
+			lines: []string{
+				`[2023.01.10-12.00.00:000][  0]LogCook: Warning: Unable to find material /Game/Materials/MI_Floor`,
+				`[2023.01.10-12.00.05:000][  1]LogCook: Warning: Unable to find material /Game/Materials/MI_Floor`,
+				`D:\Build\Metaverse\Source\Metaverse\Private\VeActor.cpp(42): warning C4996: 'Foo': was declared deprecated`,
+				`D:/Build/Metaverse/Source/Metaverse/Private/VeActor.cpp(42): warning C4996: 'Foo': was declared deprecated`,
+				`LogOutputDevice: Error: Ensure condition failed: Component != nullptr`,
+				`LogOutputDevice: Error: [Callstack] 0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::BeginPlay()`,
+				`LogOutputDevice: Error: Ensure condition failed: Component != nullptr`,
+				`LogOutputDevice: Error: [Callstack] 0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::BeginPlay()`,
+			},
+			entries: []LogEntry{
+				{Severity: LogSeverityWarning, Kind: LogKindCook, Category: "Cook", Asset: "/Game/Materials/MI_Floor", Message: "Unable to find material /Game/Materials/MI_Floor", Count: 2},
+				{Severity: LogSeverityWarning, Kind: LogKindCompiler, File: "D:/Build/Metaverse/Source/Metaverse/Private/VeActor.cpp", Line: 42, Code: "C4996", Message: "'Foo': was declared deprecated", Count: 2},
+				{Severity: LogSeverityError, Kind: LogKindEnsure, Category: "OutputDevice", Message: "Ensure condition failed: Component != nullptr", Count: 2, Callstack: []string{
+					`0x00007ffb3c1a2c00 UnrealEditor-Metaverse.dll!AVeActor::BeginPlay()`,
+				}},
+			},
+			exitCode: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewUnrealLogParser()
+			for _, line := range tt.lines {
+				p.ParseLine(line + "\r\n")
+			}
+
+			var entries []LogEntry
+			for _, e := range p.Entries() {
+				entry := *e
+				entry.KnownError = nil
+				entries = append(entries, entry)
+			}
+
+			if !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("entries = %+v, want %+v", entries, tt.entries)
+			}
+
+			exitCode := -1
+			if p.ExitCode != nil {
+				exitCode = *p.ExitCode
+			}
+			if exitCode != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", exitCode, tt.exitCode)
+			}
+		})
+	}
+}
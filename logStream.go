@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogStreamBatchSize     = 500
+	defaultLogStreamBufferSize    = 20000
+	defaultLogStreamFlushInterval = 2 * time.Second
+	logStreamMaxRetries           = 3
+	logStreamCloseTimeout         = 30 * time.Second
+)
+
+// jobLogStream forwards the output of the external tools to the API in batches while the job runs.
+// Lines are buffered in memory, if the API is slower than the output the buffer fills up and new lines are dropped and counted
+// instead of blocking the build. Close waits a limited time for the buffered lines, the lines left after that are dropped and counted.
+type jobLogStream struct {
+	job           JobMetadata
+	lines         chan JobLogLine
+	batchSize     int
+	flushInterval time.Duration
+	offset        int // Number of lines sent or dropped before the current batch
+
+	mu      sync.Mutex
+	dropped int // Lines dropped since the last batch
+	closed  bool
+
+	stop chan struct{} // Closed when Close gives up waiting for the buffered lines
+	done chan struct{}
+}
+
+var (
+	activeJobLogStreamMu sync.Mutex
+	activeJobLogStream   *jobLogStream // Stream of the job being processed
+)
+
+// startJobLogStream starts streaming the job log and makes it the active stream, returns nil if streaming is disabled
+func startJobLogStream(job JobMetadata) *jobLogStream {
+	if config.LogStream.Disabled || job.Id == nil {
+		return nil
+	}
+
+	s := &jobLogStream{
+		job:           job,
+		lines:         make(chan JobLogLine, defaultLogStreamBufferSize),
+		batchSize:     defaultLogStreamBatchSize,
+		flushInterval: defaultLogStreamFlushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	if config.LogStream.BufferSize > 0 {
+		s.lines = make(chan JobLogLine, config.LogStream.BufferSize)
+	}
+
+	if config.LogStream.BatchSize > 0 {
+		s.batchSize = config.LogStream.BatchSize
+	}
+
+	if config.LogStream.FlushInterval > 0 {
+		s.flushInterval = time.Duration(config.LogStream.FlushInterval)
+	}
+
+	go s.run()
+
+	activeJobLogStreamMu.Lock()
+	activeJobLogStream = s
+	activeJobLogStreamMu.Unlock()
+
+	return s
+}
+
+// jobLogWriter returns the function forwarding the tool output lines to the active stream of the job, lines are discarded if there is no stream
+func jobLogWriter(job JobMetadata, source string) func(line string) {
+	activeJobLogStreamMu.Lock()
+	s := activeJobLogStream
+	activeJobLogStreamMu.Unlock()
+
+	if s == nil || job.Id == nil || s.job.Id == nil || *s.job.Id != *job.Id {
+		return func(string) {}
+	}
+
+	return func(line string) {
+		s.Write(source, line)
+	}
+}
+
+// Write queues the line without blocking, the line is dropped if the buffer is full
+func (s *jobLogStream) Write(source string, line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.lines <- JobLogLine{Source: source, Time: time.Now().UTC(), Text: line}:
+	default:
+		s.dropped++
+	}
+}
+
+// Close flushes the buffered lines and stops the stream. If the lines are not sent before the deadline the rest are dropped and only
+// their count is sent without retries.
+func (s *jobLogStream) Close() {
+	if s == nil {
+		return
+	}
+
+	activeJobLogStreamMu.Lock()
+	if activeJobLogStream == s {
+		activeJobLogStream = nil
+	}
+	activeJobLogStreamMu.Unlock()
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.lines)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	case <-time.After(logStreamCloseTimeout):
+	}
+
+	close(s.stop)
+
+	select {
+	case <-s.done:
+	case <-time.After(logStreamCloseTimeout):
+		Logger.Warningf("job log stream did not stop in time, the remaining lines are lost")
+	}
+}
+
+// run sends the queued lines in batches when the batch is full or the flush interval passes
+func (s *jobLogStream) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	var batch []JobLogLine
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.flush(batch, logStreamMaxRetries)
+				return
+			}
+			batch = append(batch, line)
+			if len(batch) >= s.batchSize {
+				s.flush(batch, logStreamMaxRetries)
+				batch = nil
+			}
+		case <-ticker.C:
+			s.flush(batch, logStreamMaxRetries)
+			batch = nil
+		case <-s.stop:
+			s.drop(len(batch))
+			s.flush(nil, 1)
+			return
+		}
+	}
+}
+
+// drop counts the lines of the unsent batch and the ones left in the buffer as dropped
+func (s *jobLogStream) drop(count int) {
+	for range s.lines {
+		count++
+	}
+
+	s.mu.Lock()
+	s.dropped += count
+	s.mu.Unlock()
+}
+
+// flush sends the batch along with the number of lines dropped before it, retrying up to attempts times if the API fails,
+// no more retries are made once the stream is stopped
+func (s *jobLogStream) flush(batch []JobLogLine, attempts int) {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if len(batch) == 0 && dropped == 0 {
+		return
+	}
+
+	request := JobLogStreamRequestMetadata{Offset: s.offset, Dropped: dropped, Lines: batch}
+	s.offset += dropped + len(batch)
+
+	var err error
+retry:
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = sendJobLogLines(s.job, request); err == nil {
+			return
+		}
+		if attempt < attempts {
+			select {
+			case <-s.stop:
+				break retry
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+
+	Logger.Warningf("failed to stream %d job log lines, dropping them: %v", len(batch), err)
+}
+
+// sendJobLogLines sends the batch of the job log lines to the API
+func sendJobLogLines(job JobMetadata, request JobLogStreamRequestMetadata) error {
+	reqUrl := fmt.Sprintf("%s/jobs/%s/log/stream", api2Url, job.Id.String())
+
+	b, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to serialize json: %s", err)
+	}
+
+	req, err := http.NewRequest("POST", reqUrl, bytes.NewBuffer(b))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %s", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		if err := body.Close(); err != nil {
+			Logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %s", err)
+		}
+		return fmt.Errorf("failed to stream job log, status code: %d, content: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
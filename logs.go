@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+// LogSummary is the result of parsing a single log file
+type LogSummary struct {
+	File     string      `json:"file"`
+	ExitCode *int        `json:"exitCode,omitempty"` // AutomationTool exit code if found in the log
+	Errors   int         `json:"errors"`             // Number of distinct errors
+	Warnings int         `json:"warnings"`           // Number of distinct warnings
+	Entries  []*LogEntry `json:"entries"`
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// newLogsCmd creates the command group to work with UAT, UBT and editor logs
+func newLogsCmd() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Work with UAT, UBT and editor logs",
+		// Does not need the API
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+	}
+
+	var (
+		format string
+		output string
+	)
+
+	parseCmd := &cobra.Command{
+		Use:   "parse <log>...",
+		Short: "Parse logs with the job log parser and print the summary as text, JSON or JUnit XML, use - to read stdin",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var summaries []LogSummary
+			for _, path := range args {
+				summary, err := parseLogFile(path)
+				if err != nil {
+					return err
+				}
+				summaries = append(summaries, summary)
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %v", output, err)
+				}
+				defer func(f *os.File) {
+					if err := f.Close(); err != nil {
+						Logger.Errorf("failed to close %s: %v", output, err)
+					}
+				}(f)
+				w = f
+			}
+
+			return writeLogSummaries(w, summaries, format)
+		},
+	}
+
+	parseCmd.Flags().StringVarP(&format, "format", "f", "text", "output format: text, json or junit")
+	parseCmd.Flags().StringVarP(&output, "output", "o", "", "output file, defaults to stdout")
+
+	logsCmd.AddCommand(parseCmd)
+
+	return logsCmd
+}
+
+// parseLogFile parses the log file line by line, "-" reads stdin
+func parseLogFile(path string) (summary LogSummary, err error) {
+	var rd io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return summary, fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		defer func(f *os.File) {
+			if err := f.Close(); err != nil {
+				Logger.Errorf("failed to close %s: %v", path, err)
+			}
+		}(f)
+		rd = f
+	}
+
+	parser := NewUnrealLogParser()
+	if err = parseLog(rd, parser); err != nil {
+		return summary, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	return newLogSummary(path, parser), nil
+}
+
+// parseLog feeds all the lines of the reader to the parser
+func parseLog(rd io.Reader, parser *UnrealLogParser) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		parser.ParseLine(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// newLogSummary summarizes the parsed entries
+func newLogSummary(path string, parser *UnrealLogParser) LogSummary {
+	summary := LogSummary{File: path, ExitCode: parser.ExitCode, Entries: parser.Entries()}
+	if summary.Entries == nil {
+		summary.Entries = []*LogEntry{}
+	}
+
+	for _, e := range summary.Entries {
+		if e.Severity == LogSeverityError {
+			summary.Errors++
+		} else {
+			summary.Warnings++
+		}
+	}
+
+	return summary
+}
+
+// writeLogSummaries writes the summaries in the requested format
+func writeLogSummaries(w io.Writer, summaries []LogSummary, format string) error {
+	switch format {
+	case "text":
+		return writeLogSummariesText(w, summaries)
+	case "json":
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(summaries)
+	case "junit":
+		return writeLogSummariesJUnit(w, summaries)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// writeLogSummariesText writes a human-readable summary
+func writeLogSummariesText(w io.Writer, summaries []LogSummary) error {
+	var b strings.Builder
+	for _, s := range summaries {
+		b.WriteString(fmt.Sprintf("%s: %d errors, %d warnings", s.File, s.Errors, s.Warnings))
+		if s.ExitCode != nil {
+			b.WriteString(fmt.Sprintf(", exit code %d", *s.ExitCode))
+		}
+		b.WriteString("\n")
+
+		for _, e := range s.Entries {
+			b.WriteString("  " + e.String() + "\n")
+			for _, frame := range e.Callstack {
+				b.WriteString("      " + frame + "\n")
+			}
+			if e.KnownError != nil {
+				b.WriteString("    " + e.KnownError.Explanation + "\n")
+				if e.KnownError.Hint != "" {
+					b.WriteString("    Hint: " + e.KnownError.Hint + "\n")
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeLogSummariesJUnit writes a JUnit XML report with a test suite per log, errors are reported as failures
+func writeLogSummariesJUnit(w io.Writer, summaries []LogSummary) error {
+	report := junitTestSuites{}
+
+	for _, s := range summaries {
+		suite := junitTestSuite{Name: s.File}
+
+		exitCodeReported := false
+		for _, e := range s.Entries {
+			tc := junitTestCase{
+				Name:      e.String(),
+				ClassName: e.Kind,
+			}
+			if e.Category != "" {
+				tc.ClassName = e.Kind + ".Log" + e.Category
+			}
+
+			details := e.Describe()
+			if len(e.Callstack) > 0 {
+				details += "\n" + strings.Join(e.Callstack, "\n")
+			}
+
+			if e.Severity == LogSeverityError {
+				tc.Failure = &junitFailure{Message: e.Message, Type: e.Kind, Text: details}
+				suite.Failures++
+				if s.ExitCode != nil && e.Kind == LogKindUAT && e.Message == uatExitCodeMessage(*s.ExitCode) {
+					exitCodeReported = true
+				}
+			} else {
+				tc.SystemOut = details
+			}
+
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		// Always report the log itself so clean logs still produce a passing test, it fails on the exit code only if no entry reported it
+		logCase := junitTestCase{Name: "log", ClassName: "UAT"}
+		if s.ExitCode != nil && *s.ExitCode != 0 && !exitCodeReported {
+			logCase.Failure = &junitFailure{Message: uatExitCodeMessage(*s.ExitCode), Type: LogKindUAT}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, logCase)
+
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
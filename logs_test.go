@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteLogSummariesJUnit(t *testing.T) {
+	exitCode := 25
+	tests := []struct {
+		name     string
+		summary  LogSummary
+		failures int
+	}{
+		{
+			name: "exit code entry",
+			summary: LogSummary{File: "UAT.log", ExitCode: &exitCode, Entries: []*LogEntry{
+				{Severity: LogSeverityWarning, Kind: LogKindCook, Category: "Cook", Message: "Unable to find material", Count: 1},
+				{Severity: LogSeverityError, Kind: LogKindUAT, Code: "Error_UnknownCookFailure", Message: uatExitCodeMessage(exitCode), Count: 1},
+			}},
+			failures: 1,
+		},
+		{
+			name:     "exit code without entry",
+			summary:  LogSummary{File: "UAT.log", ExitCode: &exitCode},
+			failures: 1,
+		},
+		{
+			name:     "clean log",
+			summary:  LogSummary{File: "UAT.log"},
+			failures: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeLogSummariesJUnit(&b, []LogSummary{tt.summary}); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+
+			var report junitTestSuites
+			if err := xml.Unmarshal(b.Bytes(), &report); err != nil {
+				t.Fatalf("failed to parse report: %v", err)
+			}
+
+			if report.Failures != tt.failures {
+				t.Errorf("failures = %d, want %d", report.Failures, tt.failures)
+			}
+			if report.Tests != len(tt.summary.Entries)+1 {
+				t.Errorf("tests = %d, want %d", report.Tests, len(tt.summary.Entries)+1)
+			}
+		})
+	}
+}
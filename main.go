@@ -11,7 +11,8 @@ import (
 
 var rootCmd *cobra.Command
 
-func init() {
+// loadEnv loads the environment required by the commands working with the API and the project
+func loadEnv() {
 	api2Url = os.Getenv("VAT_API2_URL")
 	if api2Url == "" {
 		Logger.Fatalln("required env VAT_API2_URL is not defined")
@@ -32,16 +33,6 @@ func init() {
 		Logger.Fatalln("required env VAT_PROJECT_NAME is not defined")
 	}
 
-	ueVersionCode = os.Getenv("VAT_UE_VERSION_CODE")
-	if ueVersionCode == "" {
-		Logger.Fatalln("required env VAT_UE_VERSION_CODE is not defined")
-	}
-
-	ueVersionMarketplace = os.Getenv("VAT_UE_VERSION_MARKETPLACE")
-	if ueVersionMarketplace == "" {
-		Logger.Fatalln("required env VAT_UE_VERSION_MARKETPLACE is not defined")
-	}
-
 	apiEmail = os.Getenv("VAT_API_EMAIL")
 	apiPassword = os.Getenv("VAT_API_PASSWORD")
 	if apiEmail == "" && apiPassword == "" {
@@ -61,20 +52,44 @@ func init() {
 		apiPassword = t[1]
 	}
 
+	if err := loadConfig(); err != nil {
+		Logger.Fatalln(err)
+	}
+
+	// Jobs select the engine from the registry if configured
+	ueVersionCode = os.Getenv("VAT_UE_VERSION_CODE")
+	if ueVersionCode == "" && len(config.Engines) == 0 {
+		Logger.Fatalln("required env VAT_UE_VERSION_CODE is not defined")
+	}
+
+	// SDK releases are associated with the job engine from the registry if configured
+	ueVersionMarketplace = os.Getenv("VAT_UE_VERSION_MARKETPLACE")
+	if ueVersionMarketplace == "" && len(config.Engines) == 0 {
+		Logger.Fatalln("required env VAT_UE_VERSION_MARKETPLACE is not defined")
+	}
+}
+
+func init() {
 	rootCmd = &cobra.Command{
 		Use: "process",
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			loadEnv()
+
+			//region Login
+			var err error
+			token, err = login()
+			if err != nil {
+				Logger.Fatalf("failed to login: %v", err)
+			}
+			//endregion
+		},
 		Run: func(_ *cobra.Command, args []string) {
 
 			uatPath = os.Getenv("VAT_UAT_PATH")
-			if uatPath == "" {
+			if uatPath == "" && len(config.Engines) == 0 {
 				Logger.Fatalln("required env VAT_UAT_PATH is not defined")
 			}
 
-			uvsPath = os.Getenv("VAT_UVS_PATH")
-			if uvsPath == "" {
-				Logger.Fatalln("required env VAT_UVS_PATH is not defined")
-			}
-
 			wailsPath = os.Getenv("VAT_WAILS_PATH")
 			if wailsPath == "" {
 				Logger.Fatalln("required env VAT_WAILS_PATH is not defined")
@@ -95,11 +110,20 @@ func init() {
 				Logger.Warningf("required env VAT_CERT_PASSWORD is not defined, app signing will be skipped")
 			}
 
+			if v := os.Getenv("VAT_RELEASE_ARCHIVE_PART_SIZE"); v != "" {
+				var err error
+				if releaseArchivePartSize, err = parseByteSize(v); err != nil {
+					Logger.Fatalf("invalid env VAT_RELEASE_ARCHIVE_PART_SIZE: %v", err)
+				}
+			}
+
 			editorPath = os.Getenv("VAT_EDITOR_PATH")
-			if editorPath == "" {
+			if editorPath == "" && len(config.Engines) == 0 {
 				Logger.Fatalln("required env VAT_EDITOR_PATH is not defined")
 			}
 
+			setDefaultEngine()
+
 			platforms = os.Getenv("VAT_PLATFORMS")
 			if platforms == "" {
 				Logger.Fatalln("required env VAT_PLATFORMS is not defined")
@@ -143,6 +167,9 @@ func init() {
 	}}
 
 	rootCmd.AddCommand(releaseCmd)
+	rootCmd.AddCommand(newFilesCmd())
+	rootCmd.AddCommand(newLogsCmd())
+	rootCmd.AddCommand(newSimulateCmd())
 }
 
 // process Main processing function, fetches the next unclaimed job and runs a corresponding processing function depending on the job type
@@ -181,6 +208,25 @@ func process() (err error) {
 
 	//endregion
 
+	//region Stream and archive the job log
+
+	jobLog := startJobLogStream(*job)
+	defer jobLog.Close()
+
+	// Archived before the stream is closed and the job status is updated so the upload is logged and the archive is available with the final status
+	jobLogs := startJobLogArchive(*job)
+	defer jobLogs.Close()
+
+	// Reported before the archive is uploaded and the job status is updated
+	jobReport := startJobReport(*job)
+	defer jobReport.Close()
+
+	// Restored before the job report so the restore is logged with the job
+	engineAssociation := startJobEngineAssociation(*job)
+	defer engineAssociation.Close()
+
+	//endregion
+
 	//region Validation
 
 	// Validate job type
@@ -201,6 +247,11 @@ func process() (err error) {
 		return
 	}
 
+	// Select the job engine
+	if err = useJobEngine(*job); err != nil {
+		return
+	}
+
 	//endregion
 
 	//region Process the job
@@ -222,6 +273,13 @@ func process() (err error) {
 		} else {
 			err = fmt.Errorf("unsupported job deployment %s for type %s", job.Deployment, job.Type)
 		}
+
+		// Successful releases within the warning budget become the baseline of the next releases
+		if err == nil {
+			if err1 := saveWarningBaseline(*job); err1 != nil {
+				Logger.Errorf("failed to save warning baseline: %v", err1)
+			}
+		}
 	} else if job.Type == "Package" {
 		if job.Package == nil {
 			return fmt.Errorf("job has no package metadata")
@@ -235,6 +293,9 @@ func process() (err error) {
 		} else {
 			err = fmt.Errorf("unsupported job deployment %s for type %s", job.Deployment, job.Type)
 		}
+	} else if job.Type == "Test" {
+		Logger.Infof("processing automation tests %s for %s %s", job.TestFilter, job.Platform, job.Configuration)
+		err = processAutomationTests(*job)
 	} else if job.Type == "Launcher" {
 		if job.App == nil {
 			return fmt.Errorf("job has no app metadata")
@@ -256,14 +317,6 @@ func process() (err error) {
 }
 
 func main() {
-	//region Login
-	var err error
-	token, err = login()
-	if err != nil {
-		Logger.Fatalf("failed to login: %v", err)
-	}
-	//endregion
-
 	if err := rootCmd.Execute(); err != nil {
 		Logger.Errorf("error executing automation tool: %v", err)
 		os.Exit(1)
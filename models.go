@@ -18,6 +18,7 @@ var SupportedJobTypes = map[string]bool{
 	"Release":  true, // App release building and deployment
 	"Package":  true, // Package processing
 	"Launcher": true, // Launcher processing
+	"Test":     true, // Automation tests
 }
 
 var SupportedJobDeployments = map[string]bool{
@@ -75,13 +76,14 @@ type Entity struct {
 type Package struct {
 	Entity
 
-	Name        string `json:"name,omitempty"`
-	Title       string `json:"title,omitempty"`
-	Summary     string `json:"summary,omitempty"`
-	Description string `json:"description,omitempty"`
-	Map         string `json:"map,omitempty"`
-	Release     string `json:"release,omitempty"` // Based on
-	Version     string `json:"version,omitempty"` // Version
+	Name          string `json:"name,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	Description   string `json:"description,omitempty"`
+	Map           string `json:"map,omitempty"`
+	Release       string `json:"release,omitempty"`       // Based on
+	Version       string `json:"version,omitempty"`       // Version
+	EngineVersion string `json:"engineVersion,omitempty"` // Engine version or id the package is cooked with, the default engine if empty
 }
 
 // Release struct
@@ -93,6 +95,7 @@ type Release struct {
 	Version        string     `json:"version,omitempty"`
 	CodeVersion    string     `json:"codeVersion,omitempty"`    // Code version
 	ContentVersion string     `json:"contentVersion,omitempty"` // Content base version
+	EngineVersion  string     `json:"engineVersion,omitempty"`  // Engine version or id the release is built with, the default engine if empty
 	Map            string     `json:"map,omitempty"`
 	Name           *string    `json:"name,omitempty"`
 	Description    *string    `json:"description,omitempty"`
@@ -122,6 +125,8 @@ type JobMetadata struct {
 	App           *App      `json:"app,omitempty"`
 	Package       *Package  `json:"package,omitempty"`
 	Release       *Release  `json:"release,omitempty"`
+	BuildProfiles []string  `json:"buildProfiles,omitempty"` // Names of the build profiles requested for the job
+	TestFilter    string    `json:"testFilter,omitempty"`    // Automation tests run by the test job, e.g. Project.Functional, all project tests if empty
 }
 
 type JobMetadataContainer struct {
@@ -135,7 +140,58 @@ type JobStatusRequestMetadata struct {
 	Message string `json:"message,omitempty"`
 }
 
+// JobLogLine is a single line of the external tool output streamed to the API
+type JobLogLine struct {
+	Source string    `json:"source"` // Tool producing the line, e.g. uat, wails or signtool
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+}
+
+// JobLogStreamRequestMetadata is a batch of the streamed job log lines
+type JobLogStreamRequestMetadata struct {
+	Offset  int          `json:"offset"`            // Number of lines sent or dropped before this batch
+	Dropped int          `json:"dropped,omitempty"` // Number of lines dropped right before this batch because the API was too slow
+	Lines   []JobLogLine `json:"lines,omitempty"`
+}
+
 type JobLogRequestMetadata struct {
-	Warnings []string `json:"warnings,omitempty"`
-	Errors   []string `json:"errors,omitempty"`
+	Warnings []string        `json:"warnings,omitempty"`
+	Errors   []string        `json:"errors,omitempty"`
+	Entries  []*LogEntry     `json:"entries,omitempty"`
+	Runs     []*JobRunReport `json:"runs,omitempty"` // External tool runs of the job in order
+
+	WarningBudget *WarningBudgetResult `json:"warningBudget,omitempty"` // Release quality gate result
+	ContentPolicy *ContentPolicyReport `json:"contentPolicy,omitempty"` // User package content scan result
+}
+
+// JobRunReport describes a single external tool run of the job
+type JobRunReport struct {
+	Tool      string         `json:"tool"`              // e.g. AutomationTool
+	Command   string         `json:"command,omitempty"` // e.g. BuildCookRun
+	Attempt   int            `json:"attempt"`           // 1 for the first run, greater for retries of transient failures
+	Started   time.Time      `json:"started"`
+	Duration  float64        `json:"duration"` // Seconds
+	ExitCode  int            `json:"exitCode"`
+	Error     string         `json:"error,omitempty"`
+	Transient string         `json:"transient,omitempty"` // Name of the transient failure rule matching the output of the failed run
+	Metrics   *BuildMetrics  `json:"metrics,omitempty"`
+	Crashes   []*CrashReport `json:"crashes,omitempty"` // Crashes and failed assertions of the failed run
+}
+
+// ReleaseArchiveIndex describes a release archive split into parts, parts are concatenated in the index order to reassemble the archive
+type ReleaseArchiveIndex struct {
+	Name     string               `json:"name"`     // Name of the reassembled archive
+	Size     int64                `json:"size"`     // Size of the reassembled archive
+	Sha256   string               `json:"sha256"`   // Checksum of the reassembled archive
+	PartSize int64                `json:"partSize"` // Maximum size of a single part
+	Method   string               `json:"method"`   // Reassembly method, parts are concatenated
+	Parts    []ReleaseArchivePart `json:"parts"`
+}
+
+// ReleaseArchivePart describes a single part of the split release archive
+type ReleaseArchivePart struct {
+	Index  int    `json:"index"` // 1-based part index
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
 }
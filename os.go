@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,35 +31,70 @@ func getPlatformName(job JobMetadata) (platform string) {
 	return
 }
 
-func listFilesRecursive(dir string, fileList []string) ([]string, error) {
-	files := []string{}
+// fileSelection is a file considered for a release or an SDK with the rule that decided whether it is included
+type fileSelection struct {
+	Path     string      // Path relative to the selection root
+	Size     int64       // File size in bytes
+	Included bool        // File is included
+	Rule     *ignoreRule // Rule that decided, nil if no rule matched
+}
+
+// selectFilesRecursive walks regular files under dir and decides for each one using the ignore rules.
+// Directories matched by the rules are pruned unless withExcluded is set, in which case their files are reported as excluded by the directory rule.
+func selectFilesRecursive(dir string, ignore ignoreRules, withExcluded bool) ([]fileSelection, error) {
+	var (
+		files           []fileSelection
+		excludedDir     string
+		excludedDirRule *ignoreRule
+	)
 
 	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
-		skip := false
+		if err != nil {
+			return fmt.Errorf("failed to walk %s: %v", path, err)
+		}
 
-		// Loop : Ignore or include Files & Folders
-		for _, i := range fileList {
-			// Ignoring list records
-			if strings.Contains(filepath.ToSlash(path), filepath.ToSlash(i)) {
-				skip = true
-			}
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
 		}
 
-		if !skip {
-			f, err = os.Stat(path)
-			if err != nil {
-				return fmt.Errorf("failed to get file info: %v", err)
-			}
+		if relativePath == "." {
+			return nil
+		}
 
-			fileMode := f.Mode()
-			if fileMode.IsRegular() {
-				relativePath, err := filepath.Rel(dir, path)
-				if err != nil {
-					return err
-				}
+		slashPath := filepath.ToSlash(relativePath)
+
+		var rule *ignoreRule
+		if excludedDirRule != nil && strings.HasPrefix(slashPath, excludedDir+"/") {
+			rule = excludedDirRule
+		} else {
+			excludedDirRule = nil
+			rule = ignore.Match(slashPath, f.IsDir())
+		}
 
-				files = append(files, relativePath)
+		included := rule == nil || rule.Negate
+
+		if f.IsDir() {
+			if !included && excludedDirRule == nil {
+				if !withExcluded {
+					return filepath.SkipDir
+				}
+				excludedDir, excludedDirRule = slashPath, rule
 			}
+			return nil
+		}
+
+		if !included && !withExcluded {
+			return nil
+		}
+
+		f, err = os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to get file info: %v", err)
+		}
+
+		if f.Mode().IsRegular() {
+			files = append(files, fileSelection{Path: relativePath, Size: f.Size(), Included: included, Rule: rule})
 		}
 
 		return nil
@@ -69,3 +105,72 @@ func listFilesRecursive(dir string, fileList []string) ([]string, error) {
 
 	return files, nil
 }
+
+// listFilesRecursive lists regular files under dir relative to it, skipping files and pruning directories matched by the ignore rules
+func listFilesRecursive(dir string, ignore ignoreRules) ([]string, error) {
+	selection, err := selectFilesRecursive(dir, ignore, false)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, f := range selection {
+		if f.Included {
+			files = append(files, f.Path)
+		}
+	}
+
+	return files, nil
+}
+
+// copyFile copies the file contents to the destination path, the destination is overwritten
+func copyFile(src string, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", src, err)
+	}
+
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			Logger.Errorf("failed to close %s: %v", src, err)
+		}
+	}(in)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", dst, err)
+	}
+
+	defer func(f *os.File) {
+		if err1 := f.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", dst, err1)
+		}
+	}(out)
+
+	if _, err = io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+
+	return nil
+}
+
+// copyDir copies the directory files recursively to the destination directory
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		return copyFile(path, target)
+	})
+}
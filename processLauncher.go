@@ -120,7 +120,7 @@ func processClientLauncher(job JobMetadata) (err error) {
 		return
 	}
 
-	if err1 := runWailsCli(commandLine, launcherDir); err1 != nil {
+	if err1 := runWailsCli(job, commandLine, launcherDir, jobLogWriter(job, "wails")); err1 != nil {
 		err = fmt.Errorf("failed to run wails cli: %v", err1)
 		return
 	}
@@ -141,7 +141,7 @@ func processClientLauncher(job JobMetadata) (err error) {
 			certPassword,
 			outPath,
 		}
-		if err1 := runSignTool(commandLine, buildBinaryDir); err1 != nil {
+		if err1 := runSignTool(job, commandLine, buildBinaryDir, jobLogWriter(job, "signtool")); err1 != nil {
 			err = fmt.Errorf("failed to run sign tool: %v", err1)
 			return
 		}
@@ -161,7 +161,7 @@ func processClientLauncher(job JobMetadata) (err error) {
 			certPassword,
 			outPath,
 		}
-		if err1 := runSignTool(commandLine, buildBinaryDir); err1 != nil {
+		if err1 := runSignTool(job, commandLine, buildBinaryDir, jobLogWriter(job, "signtool")); err1 != nil {
 			err = fmt.Errorf("failed to run sign tool: %v", err1)
 			return
 		}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -8,6 +9,9 @@ import (
 	"strings"
 )
 
+// packageMarkerFile marks the plugin directories created by the package jobs, only these directories are replaced or removed
+const packageMarkerFile = ".veverse-automation-package"
+
 // uploadPackageFile uploads the package job results to the API for storage
 func uploadPackageFile(job JobMetadata, path string, params map[string]string) error {
 	Logger.Infof("uploading package file %s", path)
@@ -40,8 +44,8 @@ func processServerPackage(job JobMetadata) (err error) {
 		return
 	}
 
-	if job.Package.Name == "" {
-		err = fmt.Errorf("invalid job package name")
+	if !validPackageName(job.Package.Name) {
+		err = fmt.Errorf("invalid job package name %q", job.Package.Name)
 		return
 	}
 
@@ -50,62 +54,7 @@ func processServerPackage(job JobMetadata) (err error) {
 		return
 	}
 
-	// Create directories as required
-	var pluginDir = filepath.Join(projectDir, "Plugins", job.Package.Name)
-	var pluginContentDir = filepath.Join(pluginDir, "Content")
-	if err1 := os.MkdirAll(pluginContentDir, 0755); err1 != nil {
-		err = fmt.Errorf("failed to create a directory %s: %s", pluginContentDir, err1)
-		return
-	}
-
-	var pluginDescriptorExists = false
-	var pluginContentZipExists = false
-	for _, f := range job.Files {
-		if f.Type == "uplugin" {
-			var err1 error = nil
-
-			// Download a plugin descriptor
-			var pluginDescriptorPath = filepath.Join(pluginDir, job.Package.Name+".uplugin")
-			if f.Size != nil {
-				err1 = downloadFile(pluginDescriptorPath, f.Url, *f.Size, true)
-			} else {
-				err1 = downloadFile(pluginDescriptorPath, f.Url, 0, true)
-			}
-
-			if err1 != nil {
-				err = fmt.Errorf("failed to download a plugin descriptor: %s", err1)
-			}
-
-			pluginDescriptorExists = true
-		} else if f.Type == "uplugin_content" {
-			var err1 error = nil
-
-			// Download a plugin content
-			var pluginContentZipPath = filepath.Join(pluginDir, job.Package.Id.String()+".zip")
-			if f.Size != nil {
-				err1 = downloadFile(pluginContentZipPath, f.Url, *f.Size, true)
-			} else {
-				err1 = downloadFile(pluginContentZipPath, f.Url, 0, true)
-			}
-
-			if err1 != nil {
-				err = fmt.Errorf("failed to download a plugin content: %v", err1)
-				return
-			}
-
-			Logger.Infof("unzipping file %s to %s", pluginContentZipPath, pluginContentDir)
-
-			if err1 = unzip(pluginContentZipPath, pluginContentDir); err != nil {
-				err = fmt.Errorf("failed to unzip a plugin content: %s", err1)
-				return
-			}
-
-			pluginContentZipExists = true
-		}
-	}
-
-	if !pluginContentZipExists || !pluginDescriptorExists {
-		err = fmt.Errorf("job is missing content zip %d or descriptor %d", pluginContentZipExists, pluginDescriptorExists)
+	if err = downloadJobPackage(job, filepath.Join(projectDir, "Plugins", job.Package.Name)); err != nil {
 		return
 	}
 
@@ -114,14 +63,21 @@ func processServerPackage(job JobMetadata) (err error) {
 		return fmt.Errorf("failed to switch engine version: %v", err)
 	}
 
-	commandLine := fmt.Sprintf("BuildCookRun -project=%s -noP4 -serverconfig=%s -unrealexe=%s -utf8output -cook -map=%s -unversionedcookedcontent -pak -dlcname=%s -DLCIncludeEngineContent -basedonreleaseversion=%s -distribution -compressed -package -noclient -server -serverplatform=%s -skipstage -VeryVerbose -BuildMachine", projectName, job.Configuration, editorPath, job.Package.Map, job.Package.Name, job.Package.Release, job.Platform)
+	command := newPackageBuildCookRun(job)
+	command.Distribution = true
+
+	args, err2 := command.Args()
+	if err2 != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err2)
+	}
 
-	if result, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err != nil {
+	args, env, err2 := applyJobBuildProfiles(job, args)
+	if err2 != nil {
+		return fmt.Errorf("failed to apply build profiles: %v", err2)
+	}
+
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, env)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
-		err1 := reportJobLog(job, result.Warnings, result.Errors)
-		if err1 != nil {
-			Logger.Errorf("failed to report job log: %v", err1)
-		}
 		return
 	}
 
@@ -173,8 +129,8 @@ func processClientPackage(job JobMetadata) (err error) {
 		return
 	}
 
-	if job.Package.Name == "" {
-		err = fmt.Errorf("invalid job package name")
+	if !validPackageName(job.Package.Name) {
+		err = fmt.Errorf("invalid job package name %q", job.Package.Name)
 		return
 	}
 
@@ -183,97 +139,28 @@ func processClientPackage(job JobMetadata) (err error) {
 		return
 	}
 
-	// Create directories as required
-	var pluginDir = filepath.Join(projectDir, "Plugins", job.Package.Name)
-	var pluginContentDir = filepath.Join(pluginDir, "Content")
-	if err1 := os.MkdirAll(pluginContentDir, 0755); err1 != nil {
-		err = fmt.Errorf("failed to create a directory %s: %s", pluginContentDir, err1)
+	if err = downloadJobPackage(job, filepath.Join(projectDir, "Plugins", job.Package.Name)); err != nil {
 		return
 	}
 
-	var pluginDescriptorExists = false
-	var pluginContentZipExists = false
-	for _, f := range job.Files {
-		if f.Type == "uplugin" {
-			var err1 error = nil
-
-			// Download a plugin descriptor
-			var pluginDescriptorPath = filepath.Join(pluginDir, job.Package.Name+".uplugin")
-			if f.Size != nil {
-				err1 = downloadFile(pluginDescriptorPath, f.Url, *f.Size, true)
-			} else {
-				err1 = downloadFile(pluginDescriptorPath, f.Url, 0, true)
-			}
-
-			if err1 != nil {
-				err = fmt.Errorf("failed to download a plugin descriptor: %s", err1)
-			}
-
-			pluginDescriptorExists = true
-		} else if f.Type == "uplugin_content" {
-			var err1 error = nil
-
-			// Download a plugin content
-			var pluginContentZipPath = filepath.Join(pluginDir, job.Package.Id.String()+".zip")
-			if f.Size != nil {
-				err1 = downloadFile(pluginContentZipPath, f.Url, *f.Size, true)
-			} else {
-				err1 = downloadFile(pluginContentZipPath, f.Url, 0, true)
-			}
-
-			if err1 != nil {
-				err = fmt.Errorf("failed to download a plugin content: %v", err1)
-				return
-			}
-
-			Logger.Infof("unzipping file %s to %s", pluginContentZipPath, pluginContentDir)
-
-			if err1 = unzip(pluginContentZipPath, pluginContentDir); err != nil {
-				err = fmt.Errorf("failed to unzip a plugin content: %s", err1)
-				return
-			}
+	command := newPackageBuildCookRun(job)
+	if job.Configuration == "Shipping" {
+		// Add -distribution for the shipping builds
+		command.Distribution = true
+	}
 
-			pluginContentZipExists = true
-		}
+	args, err2 := command.Args()
+	if err2 != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err2)
 	}
 
-	if !pluginContentZipExists || !pluginDescriptorExists {
-		err = fmt.Errorf("job is missing content zip %d or descriptor %d", pluginContentZipExists, pluginDescriptorExists)
-		return
+	args, env, err2 := applyJobBuildProfiles(job, args)
+	if err2 != nil {
+		return fmt.Errorf("failed to apply build profiles: %v", err2)
 	}
 
-	var commandLine string
-	if job.Configuration == "Shipping" {
-		// Add -distribution for the shipping builds
-		commandLine = fmt.Sprintf(
-			"BuildCookRun -project=%s -noP4 -clientconfig=%s -unrealexe=%s -utf8output -platform=%s -cook -map=%s -unversionedcookedcontent -pak -dlcname=%s -DLCIncludeEngineContent -basedonreleaseversion=%s -compressed -package -skipstage -VeryVerbose -BuildMachine -distribution",
-			projectName,
-			job.Configuration,
-			editorPath,
-			job.Platform,
-			job.Package.Map,
-			job.Package.Name,
-			job.Package.Release,
-		)
-	} else {
-		commandLine = fmt.Sprintf(
-			"BuildCookRun -project=%s -noP4 -clientconfig=%s -unrealexe=%s -utf8output -platform=%s -cook -map=%s -unversionedcookedcontent -pak -dlcname=%s -DLCIncludeEngineContent -basedonreleaseversion=%s -compressed -package -skipstage -VeryVerbose -BuildMachine",
-			projectName,
-			job.Configuration,
-			editorPath,
-			job.Platform,
-			job.Package.Map,
-			job.Package.Name,
-			job.Package.Release,
-		)
-	}
-
-	if result, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err1 != nil {
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, env)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
-		err1 := reportJobLog(job, result.Warnings, result.Errors)
-		if err1 != nil {
-			Logger.Errorf("failed to report job log: %v", err1)
-		}
 		return
 	}
 
@@ -331,3 +218,127 @@ func processClientPackage(job JobMetadata) (err error) {
 
 	return
 }
+
+// downloadJobPackage downloads the package descriptor and content to the plugin directory and unpacks the content.
+// The descriptor is validated before the content is downloaded, the plugin directory is removed if the package is rejected.
+func downloadJobPackage(job JobMetadata, pluginDir string) (err error) {
+	var pluginDescriptorExists = false
+	var pluginContentZipExists = false
+	for _, f := range job.Files {
+		if f.Type == "uplugin" {
+			pluginDescriptorExists = true
+		} else if f.Type == "uplugin_content" {
+			pluginContentZipExists = true
+		}
+	}
+
+	if !pluginContentZipExists || !pluginDescriptorExists {
+		return fmt.Errorf("job is missing content zip %v or descriptor %v", pluginContentZipExists, pluginDescriptorExists)
+	}
+
+	// Create directories as required
+	if err = preparePackageDir(pluginDir); err != nil {
+		return
+	}
+	var pluginContentDir = filepath.Join(pluginDir, "Content")
+
+	// Download a plugin descriptor
+	var pluginDescriptorPath = filepath.Join(pluginDir, job.Package.Name+".uplugin")
+	for _, f := range job.Files {
+		if f.Type != "uplugin" {
+			continue
+		}
+
+		var err1 error = nil
+		if f.Size != nil {
+			err1 = downloadFile(pluginDescriptorPath, f.Url, *f.Size, true)
+		} else {
+			err1 = downloadFile(pluginDescriptorPath, f.Url, 0, true)
+		}
+
+		if err1 != nil {
+			return fmt.Errorf("failed to download a plugin descriptor: %s", err1)
+		}
+	}
+
+	// The descriptor is checked before the content is downloaded and unpacked into the project
+	if err = validatePackageDescriptor(job, pluginDescriptorPath); err != nil {
+		removeRejectedPackage(pluginDir)
+		return
+	}
+
+	for _, f := range job.Files {
+		if f.Type != "uplugin_content" {
+			continue
+		}
+
+		var err1 error = nil
+
+		// Download a plugin content
+		var pluginContentZipPath = filepath.Join(pluginDir, job.Package.Id.String()+".zip")
+		if f.Size != nil {
+			err1 = downloadFile(pluginContentZipPath, f.Url, *f.Size, true)
+		} else {
+			err1 = downloadFile(pluginContentZipPath, f.Url, 0, true)
+		}
+
+		if err1 != nil {
+			return fmt.Errorf("failed to download a plugin content: %v", err1)
+		}
+
+		// User content is checked before anything is unpacked into the project
+		if err = checkPackageContent(job, pluginContentZipPath); err != nil {
+			removeRejectedPackage(pluginDir)
+			return
+		}
+
+		Logger.Infof("unzipping file %s to %s", pluginContentZipPath, pluginContentDir)
+
+		if err1 = unzip(pluginContentZipPath, pluginContentDir); err1 != nil {
+			return fmt.Errorf("failed to unzip a plugin content: %s", err1)
+		}
+	}
+
+	return nil
+}
+
+// validPackageName checks that the package name is a single path element, the name is the plugin directory in the project
+func validPackageName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\:")
+}
+
+// preparePackageDir creates the plugin directory of the package with the marker file of the package jobs.
+// A directory left by a previous package job is replaced, any other existing directory, e.g. a plugin of the project, is refused.
+func preparePackageDir(pluginDir string) error {
+	if _, err := os.Stat(pluginDir); err == nil {
+		if _, err = os.Stat(filepath.Join(pluginDir, packageMarkerFile)); err != nil {
+			return fmt.Errorf("plugin directory %s already exists and was not created by a package job", pluginDir)
+		}
+
+		Logger.Infof("removing package %s left by a previous job", pluginDir)
+		if err = os.RemoveAll(pluginDir); err != nil {
+			return fmt.Errorf("failed to remove a directory %s: %v", pluginDir, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat a directory %s: %v", pluginDir, err)
+	}
+
+	var pluginContentDir = filepath.Join(pluginDir, "Content")
+	if err := os.MkdirAll(pluginContentDir, 0755); err != nil {
+		return fmt.Errorf("failed to create a directory %s: %v", pluginContentDir, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(pluginDir, packageMarkerFile), nil, 0644); err != nil {
+		return fmt.Errorf("failed to write the package marker: %v", err)
+	}
+
+	return nil
+}
+
+// removeRejectedPackage removes the plugin directory of the rejected package so nothing of it stays in the project
+func removeRejectedPackage(pluginDir string) {
+	Logger.Infof("removing rejected package %s", pluginDir)
+	if err := os.RemoveAll(pluginDir); err != nil {
+		Logger.Errorf("failed to remove rejected package %s: %v", pluginDir, err)
+	}
+}
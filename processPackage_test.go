@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidPackageName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"Forest", true},
+		{"Forest_Glade-2", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../Metaverse", false},
+		{"Forest/Glade", false},
+		{"Forest\\Glade", false},
+		{"C:Forest", false},
+	}
+
+	for _, tt := range tests {
+		if got := validPackageName(tt.name); got != tt.valid {
+			t.Errorf("validPackageName(%q) = %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+}
+
+func TestPreparePackageDir(t *testing.T) {
+	plugins := t.TempDir()
+
+	// A plugin of the project is never replaced
+	project := filepath.Join(plugins, "VeGame")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("failed to create the project plugin: %v", err)
+	}
+	if err := preparePackageDir(project); err == nil {
+		t.Error("expected an error for the existing project plugin")
+	}
+	if _, err := os.Stat(project); err != nil {
+		t.Errorf("project plugin removed: %v", err)
+	}
+
+	// A package left by a previous job is replaced
+	pluginDir := filepath.Join(plugins, "Forest")
+	if err := preparePackageDir(pluginDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := filepath.Join(pluginDir, "Content", "Stale.uasset")
+	if err := os.WriteFile(stale, []byte("stale"), 0644); err != nil {
+		t.Fatalf("failed to write the stale file: %v", err)
+	}
+	if err := preparePackageDir(pluginDir); err != nil {
+		t.Fatalf("unexpected error for the previous package: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale package file kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pluginDir, packageMarkerFile)); err != nil {
+		t.Errorf("package marker missing: %v", err)
+	}
+}
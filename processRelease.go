@@ -1,47 +1,25 @@
 package main
 
 import (
-	"context"
+	"bytes"
 	"fmt"
 	"github.com/gabriel-vasile/mimetype"
-	"github.com/mholt/archiver/v4"
 	"io"
 	"net/url"
-	"os"
 	"path/filepath"
 	goRuntime "runtime"
-	"strings"
 )
 
-func getReleaseIgnoredFiles() ([]string, error) {
+// getReleaseIgnoredFiles reads gitignore-style rules of files excluded from the job release
+func getReleaseIgnoredFiles(job JobMetadata) (ignoreRules, error) {
 	const ignoreFile = ".veverse-automation-ignore"
 
-	file, err := os.OpenFile(ignoreFile, os.O_RDONLY, 0644)
+	rules, err := loadIgnoreRules(ignoreFile)
 	if err != nil {
-		if err != os.ErrNotExist {
-			return nil, fmt.Errorf("failed to open %s file: %v", ignoreFile, err)
-		} else {
-			return nil, nil
-		}
-	}
-
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from %s file: %v", ignoreFile, err)
-	}
-
-	// Split lines
-	list := strings.Split(string(data), "\n")
-
-	// Filter empty lines and comments
-	var result []string
-	for _, str := range list {
-		if str != "" || !strings.HasPrefix(str, "#") {
-			result = append(result, strings.TrimSpace(str))
-		}
+		return nil, err
 	}
 
-	return result, nil
+	return rules.Select(jobRuleValues(job)), nil
 }
 
 // processServerRelease runs the server release processing
@@ -102,35 +80,37 @@ func processServerRelease(job JobMetadata) (err error) {
 	}
 
 	projectStagingDir := filepath.Join(projectDir, "Saved", "StagedBuilds")
-	commandLine := fmt.Sprintf(
-		"BuildCookRun -project=%s -noP4 -unrealexe=%s -noclient -server -serverconfig=%s -serverplatform=%s -ini:Game:[/Script/UnrealEd.ProjectPackagingSettings]:BlueprintNativizationMethod=Disabled -build -cook -unversionedcookedcontent -SkipCookingEditorContent -map=%s -pak -compressed -package -createreleaseversion=%s -stage -stagingdirectory=%s -VeryVerbose -NoCodeSign -BuildMachine -AllowCommandletRendering -utf8output",
-		projectName,
-		editorPath,
-		job.Configuration,
-		job.Platform,
-		job.Release.Map,
-		job.Release.ContentVersion,
-		projectStagingDir,
-	)
+	args, err := newReleaseBuildCookRun(job, projectStagingDir).Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
 
-	if job.Configuration == "Shipping" {
-		commandLine += " -CrashReporter -nodebug -nodebuginfo -distribution -prereqs"
-	} else if job.Configuration == "Development" || job.Configuration == "DebugGame" || job.Configuration == "Debug" || job.Configuration == "Test" {
-		commandLine += " -debug"
+	args, env, err := applyJobBuildProfiles(job, args)
+	if err != nil {
+		return fmt.Errorf("failed to apply build profiles: %v", err)
 	}
 
-	if _, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err1 != nil {
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, env)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
 		return
 	}
 
-	ignoredFiles, err2 := getReleaseIgnoredFiles()
+	if err = checkWarningBudget(job); err != nil {
+		return
+	}
+
+	ignoredFiles, err2 := getReleaseIgnoredFiles(job)
 	if err2 != nil {
 		err = fmt.Errorf("failed to get ignored files: %v", err2)
 		return
 	}
 
 	platformStagingDir := filepath.Join(projectStagingDir, getPlatformName(job))
+
+	if err = smokeTestServerRelease(job, platformStagingDir); err != nil {
+		return fmt.Errorf("server smoke test failed: %v", err)
+	}
+
 	files, err := listFilesRecursive(platformStagingDir, ignoredFiles)
 
 	for _, file := range files {
@@ -205,29 +185,26 @@ func processClientRelease(job JobMetadata) (err error) {
 	}
 
 	projectStagingDir := filepath.Join(projectDir, "Saved", "StagedBuilds")
-	commandLine := fmt.Sprintf(
-		"BuildCookRun -project=%s -noP4 -unrealexe=%s -clientconfig=%s -platform=%s -ini:Game:[/Script/UnrealEd.ProjectPackagingSettings]:BlueprintNativizationMethod=Disabled -build -cook -unversionedcookedcontent -SkipCookingEditorContent -map=%s -pak -compressed -package -createreleaseversion=%s -stage -stagingdirectory=%s -VeryVerbose -NoCodeSign -BuildMachine -AllowCommandletRendering -utf8output",
-		projectName,
-		editorPath,
-		job.Configuration,
-		job.Platform,
-		job.Release.Map,
-		job.Release.ContentVersion,
-		projectStagingDir,
-	)
+	args, err := newReleaseBuildCookRun(job, projectStagingDir).Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
 
-	if job.Configuration == "Shipping" {
-		commandLine += " -CrashReporter -nodebug -nodebuginfo -distribution -prereqs"
-	} else if job.Configuration == "Development" || job.Configuration == "DebugGame" || job.Configuration == "Debug" || job.Configuration == "Test" {
-		commandLine += " -debug"
+	args, env, err := applyJobBuildProfiles(job, args)
+	if err != nil {
+		return fmt.Errorf("failed to apply build profiles: %v", err)
 	}
 
-	if _, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err1 != nil {
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, env)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
 		return
 	}
 
-	ignore, err2 := getReleaseIgnoredFiles()
+	if err = checkWarningBudget(job); err != nil {
+		return
+	}
+
+	ignore, err2 := getReleaseIgnoredFiles(job)
 	if err2 != nil {
 		return err2
 	}
@@ -247,32 +224,11 @@ func processClientRelease(job JobMetadata) (err error) {
 	}
 
 	zipName := fmt.Sprintf("%s-%s-%s-%s-%s.zip", job.Release.AppName, job.Release.Version, job.Deployment, job.Configuration, job.Platform)
-	zip, err := os.Create(zipName)
-	if err != nil {
-		return fmt.Errorf("failed to create a zip file: %v", err)
-	}
-	defer func(zip *os.File) {
-		err := zip.Close()
-		if err != nil {
-			Logger.Errorf("failed to close a zip file: %v", err)
-		}
-	}(zip)
-
-	format := archiver.CompressedArchive{
-		Archival: archiver.Zip{},
-	}
-
-	releaseArchiveFiles, err := archiver.FilesFromDisk(nil, releaseArchiveFileMap)
-	if err != nil {
-		return fmt.Errorf("failed to enumerate release archive files to zip: %v", err)
-	}
-
-	err = format.Archive(context.Background(), zip, releaseArchiveFiles)
-	if err != nil {
+	if err = zipFiles(zipName, releaseArchiveFileMap); err != nil {
 		return fmt.Errorf("failed to zip release archive files: %v", err)
 	}
 
-	err = uploadReleaseArchiveFile(job, zipName, zipName, nil)
+	err = uploadReleaseArchive(job, zipName)
 	if err != nil {
 		return fmt.Errorf("failed to upload release archive file: %v", err)
 	}
@@ -331,3 +287,35 @@ func uploadReleaseArchiveFile(job JobMetadata, path string, originalPath string,
 
 	return uploadJobEntityFile(job, job.Release.Id, fileType, fileMime, path, originalPath, params)
 }
+
+// uploadReleaseArchivePart uploads a part of the split release archive file to the API for storage
+func uploadReleaseArchivePart(job JobMetadata, part io.Reader, size int64, originalPath string, params map[string]string) error {
+	Logger.Infof("uploading release archive part %s", originalPath)
+
+	if job.Release.Id == nil || job.Release.Id.IsNil() {
+		return fmt.Errorf("invalid job release id")
+	}
+
+	var (
+		fileType = "release-archive-part"
+		fileMime = url.QueryEscape("application/octet-stream")
+	)
+
+	return uploadJobEntityReader(job, job.Release.Id, fileType, fileMime, part, size, originalPath, originalPath, params)
+}
+
+// uploadReleaseArchiveIndex uploads the index document of the split release archive to the API for storage
+func uploadReleaseArchiveIndex(job JobMetadata, index []byte, originalPath string, params map[string]string) error {
+	Logger.Infof("uploading release archive index %s", originalPath)
+
+	if job.Release.Id == nil || job.Release.Id.IsNil() {
+		return fmt.Errorf("invalid job release id")
+	}
+
+	var (
+		fileType = "release-archive-index"
+		fileMime = url.QueryEscape("application/json")
+	)
+
+	return uploadJobEntityReader(job, job.Release.Id, fileType, fileMime, bytes.NewReader(index), int64(len(index)), originalPath, originalPath, params)
+}
@@ -1,83 +1,51 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"github.com/gabriel-vasile/mimetype"
-	"github.com/mholt/archiver/v4"
-	"io"
 	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
-func getSdkIncludeFiles(err error) ([]string, error) {
+// getSdkIncludeFiles reads gitignore-style rules of project files included into the job SDK
+func getSdkIncludeFiles(job JobMetadata) (ignoreRules, error) {
 	const includeFile = ".veverse-automation-sdk-include"
 
-	file, err := os.OpenFile(includeFile, os.O_RDONLY, 0644)
+	rules, err := loadIgnoreRules(includeFile)
 	if err != nil {
-		if err != os.ErrNotExist {
-			return nil, fmt.Errorf("failed to open %s file: %v", includeFile, err)
-		} else {
-			return nil, nil
-		}
-	}
-
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from %s file: %v", includeFile, err)
-	}
-
-	// Split lines
-	list := strings.Split(string(data), "\n")
-
-	// Filter empty lines and comments
-	var result []string
-	for _, str := range list {
-		if str != "" || !strings.HasPrefix(str, "#") {
-			result = append(result, strings.TrimSpace(str))
-		}
+		return nil, err
 	}
 
-	return result, nil
+	return rules.Select(jobRuleValues(job)), nil
 }
 
-func listSdkFilesRecursive(dir string, fileList []string, projectName string) ([]string, error) {
-	files := []string{}
+// selectSdkFilesRecursive walks regular files under dir and includes the ones matched by the include rules directly or by a parent directory,
+// directories no rule can include anything from are skipped
+func selectSdkFilesRecursive(dir string, include ignoreRules) ([]fileSelection, error) {
+	var files []fileSelection
 
 	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
-		skip := false
-
-		// Loop : Ignore or include Files & Folders
-		for _, i := range fileList {
-			left := filepath.ToSlash(path)
-			right := filepath.ToSlash(filepath.Join("UnrealEngine", projectName, i))
-
-			// Including list records
-			if strings.Contains(left, right) {
-				skip = false
-				break
-			} else {
-				skip = true
-			}
+		if err != nil {
+			return fmt.Errorf("failed to walk %s: %v", path, err)
 		}
 
-		if !skip {
-			f, err = os.Stat(path)
-			if err != nil {
-				return fmt.Errorf("failed to get file info: %v", err)
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() {
+			if relativePath != "." && !include.MayInclude(filepath.ToSlash(relativePath)) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			fileMode := f.Mode()
-			if fileMode.IsRegular() {
-				relativePath, err := filepath.Rel(dir, path)
-				if err != nil {
-					return err
-				}
+		rule := include.MatchWithParents(filepath.ToSlash(relativePath))
 
-				files = append(files, relativePath)
-			}
+		if f.Mode().IsRegular() {
+			files = append(files, fileSelection{Path: relativePath, Size: f.Size(), Included: rule != nil && !rule.Negate, Rule: rule})
 		}
 
 		return nil
@@ -89,19 +57,21 @@ func listSdkFilesRecursive(dir string, fileList []string, projectName string) ([
 	return files, nil
 }
 
-func switchProjectEngineVersion(versionOrFolderPath string) error {
-	Logger.Infof("switching engine version to %s", versionOrFolderPath)
-
-	// Get path to the project descriptor file
-	projectDescriptorPath := filepath.Join(projectDir, projectName+".uproject")
-
-	// Switch engine version
-	err := runUnrealVersionSelector([]string{"-switchversionsilent", projectDescriptorPath, versionOrFolderPath})
+// listSdkFilesRecursive lists regular files under dir relative to it which are matched by the include rules directly or by a parent directory
+func listSdkFilesRecursive(dir string, include ignoreRules) ([]string, error) {
+	selection, err := selectSdkFilesRecursive(dir, include)
 	if err != nil {
-		return fmt.Errorf("failed to switch engine version: %v", err)
+		return nil, err
 	}
 
-	return nil
+	files := []string{}
+	for _, f := range selection {
+		if f.Included {
+			files = append(files, f.Path)
+		}
+	}
+
+	return files, nil
 }
 
 // processSdkRelease runs the SDK release processing
@@ -144,42 +114,62 @@ func processSdkRelease(job JobMetadata) (err error) {
 		return fmt.Errorf("failed to update the repo: %v", err)
 	}
 
-	// Switch project to marketplace version
-	if err = switchProjectEngineVersion(ueVersionMarketplace); err != nil {
+	// Switch project to the engine the SDK is built with, the marketplace version if there is no engine registry
+	association := ueVersionMarketplace
+	if len(config.Engines) > 0 && jobEngine != nil {
+		association = jobEngine.Id
+	}
+	if err = switchProjectEngineVersion(association); err != nil {
 		return fmt.Errorf("failed to switch engine version: %v", err)
 	}
 
 	// Build editor
-	commandLine := fmt.Sprintf("BuildEditor -project=%s", projectName)
-	if _, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err1 != nil {
+	args, err := BuildEditor{Project: projectName}.Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
+
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, nil)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
 		return
 	}
 
 	// Build development release
 	projectStagingDir := filepath.Join(projectDir, "Saved", "StagedBuilds")
-	commandLine = fmt.Sprintf(
-		"BuildCookRun -project=%s -noP4 -unrealexe=%s -clientconfig=Development -serveconfig=Development -platform=%s -ini:Game:[/Script/UnrealEd.ProjectPackagingSettings]:BlueprintNativizationMethod=Disabled -build -cook -unversionedcookedcontent -SkipCookingEditorContent -map=%s -pak -compressed -package -createreleaseversion=%s -stage -stagingdirectory=%s -VeryVerbose -NoCodeSign -BuildMachine -AllowCommandletRendering -utf8output -nodebug -nodebuginfo",
-		projectName,
-		editorPath,
-		job.Platform,
-		job.Release.Map,
-		job.Release.ContentVersion,
-		projectStagingDir,
-	)
+	command := newReleaseBuildCookRun(job, projectStagingDir)
+	command.setTarget(job, "Development")
+	command.NoDebug = true
+	command.NoDebugInfo = true
+
+	args, err = command.Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
+
+	args, env, err := applyJobBuildProfiles(job, args)
+	if err != nil {
+		return fmt.Errorf("failed to apply build profiles: %v", err)
+	}
 
-	if _, err1 := runUnrealAutomationTool(strings.Split(commandLine, " ")); err1 != nil {
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, env)); err1 != nil {
 		err = fmt.Errorf("failed to run AutomationTool: %v", err1)
 		return
 	}
 
-	includeFiles, err2 := getSdkIncludeFiles(err)
+	if err = checkWarningBudget(job); err != nil {
+		return
+	}
+
+	includeFiles, err2 := getSdkIncludeFiles(job)
 	if err2 != nil {
 		return err2
 	}
 
 	platformStagingDir := projectDir
-	files, err := listSdkFilesRecursive(platformStagingDir, includeFiles, projectName)
+	files, err := listSdkFilesRecursive(platformStagingDir, includeFiles)
+	if err != nil {
+		return fmt.Errorf("failed to list SDK files: %v", err)
+	}
 
 	var releaseArchiveFileMap = map[string]string{}
 	for _, file := range files {
@@ -193,28 +183,7 @@ func processSdkRelease(job JobMetadata) (err error) {
 	}
 
 	zipName := fmt.Sprintf("%s-%s-%s-%s-%s-SDK.zip", job.Release.AppName, job.Release.Version, job.Deployment, job.Configuration, job.Platform)
-	zip, err := os.Create(zipName)
-	if err != nil {
-		return fmt.Errorf("failed to create a zip file: %v", err)
-	}
-	defer func(zip *os.File) {
-		err := zip.Close()
-		if err != nil {
-			Logger.Errorf("failed to close a zip file: %v", err)
-		}
-	}(zip)
-
-	format := archiver.CompressedArchive{
-		Archival: archiver.Zip{},
-	}
-
-	releaseArchiveFiles, err := archiver.FilesFromDisk(nil, releaseArchiveFileMap)
-	if err != nil {
-		return fmt.Errorf("failed to enumerate release archive files to zip: %v", err)
-	}
-
-	err = format.Archive(context.Background(), zip, releaseArchiveFiles)
-	if err != nil {
+	if err = zipFiles(zipName, releaseArchiveFileMap); err != nil {
 		return fmt.Errorf("failed to zip release archive files: %v", err)
 	}
 
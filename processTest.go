@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	goRuntime "runtime"
+	"strings"
+)
+
+const (
+	// defaultTestFilter runs the project tests unless the job requests another filter
+	defaultTestFilter = "Project"
+
+	// automationTestReportFile is the report exported by the engine to the report export path
+	automationTestReportFile = "index.json"
+
+	// automationTestsListLimit is the maximum number of failed tests listed in the job error
+	automationTestsListLimit = 20
+)
+
+// AutomationTestReport is the JSON report exported by the engine automation tests
+type AutomationTestReport struct {
+	Succeeded             int                   `json:"succeeded"`
+	SucceededWithWarnings int                   `json:"succeededWithWarnings"`
+	Failed                int                   `json:"failed"`
+	NotRun                int                   `json:"notRun"`
+	TotalDuration         float64               `json:"totalDuration"`
+	Tests                 []*AutomationTestItem `json:"tests"`
+}
+
+// AutomationTestItem is a single test of the automation test report
+type AutomationTestItem struct {
+	TestDisplayName string                 `json:"testDisplayName"`
+	FullTestPath    string                 `json:"fullTestPath"`
+	State           string                 `json:"state"` // Success, Fail, NotRun or InProcess
+	Warnings        int                    `json:"warnings"`
+	Errors          int                    `json:"errors"`
+	Duration        float64                `json:"duration,omitempty"`
+	Entries         []*AutomationTestEntry `json:"entries,omitempty"`
+}
+
+// AutomationTestEntry is a message logged by the test
+type AutomationTestEntry struct {
+	Event struct {
+		Type    string `json:"type"` // Info, Warning or Error
+		Message string `json:"message"`
+	} `json:"event"`
+	Filename   string `json:"filename,omitempty"`
+	LineNumber int    `json:"lineNumber,omitempty"`
+}
+
+// junitSkipped marks a test which did not run
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// processAutomationTests runs the project automation tests in the editor, uploads the test report and fails the job if any test fails
+func processAutomationTests(job JobMetadata) (err error) {
+	if err1 := updateJobStatus(job, JobStatusProcessing, ""); err1 != nil {
+		Logger.Errorf("failed to update job status: %v", err1)
+	}
+
+	if job.Id == nil || job.Id.IsNil() {
+		return fmt.Errorf("invalid job id")
+	}
+
+	r, err := gitRepo(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to open the repo at %s: %v", projectDir, err)
+	}
+
+	// Checkout the branch corresponding to the job configuration
+	if targetBranch, ok := configurationBranchMapping[job.Configuration]; !ok {
+		return fmt.Errorf("failed to map the job configuration %s to a branch", job.Configuration)
+	} else {
+		currentBranch, err := gitBranch(r)
+		if err != nil {
+			return fmt.Errorf("failed to get the current branch name: %v", err)
+		}
+
+		if //goland:noinspection GoBoolExpressions
+		goRuntime.GOOS == "darwin" {
+			targetBranch = targetBranch + "-mac"
+		}
+
+		if currentBranch != targetBranch {
+			Logger.Infof("current branch %s, checking out branch %s", currentBranch, targetBranch)
+			if err = gitCheckout(r, targetBranch); err != nil {
+				return fmt.Errorf("failed to checkout the target branch: %v", err)
+			}
+		}
+	}
+
+	if err = gitPull(r); err != nil {
+		return fmt.Errorf("failed to update the repo: %v", err)
+	}
+
+	// Switch project to code version
+	if err = switchProjectEngineVersion(ueVersionCode); err != nil {
+		return fmt.Errorf("failed to switch engine version: %v", err)
+	}
+
+	// Build editor with the test modules
+	args, err := BuildEditor{Project: projectName}.Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
+
+	if _, err1 := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, nil)); err1 != nil {
+		return fmt.Errorf("failed to run AutomationTool: %v", err1)
+	}
+
+	reportDir := filepath.Join(projectDir, "Saved", "Automation", "Reports", job.Id.String())
+	if err = os.RemoveAll(reportDir); err != nil {
+		return fmt.Errorf("failed to clean the test report directory %s: %v", reportDir, err)
+	}
+
+	filter := job.TestFilter
+	if filter == "" {
+		filter = defaultTestFilter
+	}
+
+	args, err = RunUnreal{
+		Project:          projectName,
+		Platform:         job.Platform,
+		Configuration:    "Development",
+		Build:            "editor",
+		Test:             "UE.EditorAutomation",
+		RunTest:          filter,
+		ReportExportPath: reportDir,
+		NullRHI:          true,
+		Unattended:       true,
+		NoP4:             true,
+		UTF8Output:       true,
+	}.Args()
+	if err != nil {
+		return fmt.Errorf("invalid AutomationTool command: %v", err)
+	}
+
+	// Failed tests make UAT fail too, the report tells which tests failed
+	_, runErr := runUnrealAutomationTool(args, newUnrealAutomationToolOptions(job, nil))
+
+	reportPath := filepath.Join(reportDir, automationTestReportFile)
+	report, err1 := loadAutomationTestReport(reportPath)
+	if err1 != nil {
+		if runErr != nil {
+			return fmt.Errorf("failed to run AutomationTool: %v", runErr)
+		}
+		return fmt.Errorf("failed to load the test report: %v", err1)
+	}
+
+	Logger.Infof("automation tests: %d succeeded, %d succeeded with warnings, %d failed, %d not run", report.Succeeded, report.SucceededWithWarnings, report.Failed, report.NotRun)
+
+	//region Upload job results
+
+	if err1 := updateJobStatus(job, JobStatusUploading, ""); err1 != nil {
+		Logger.Errorf("failed to update job status: %v", err1)
+	}
+
+	if err1 = uploadTestReportFile(job, reportPath, "application/json", nil); err1 != nil {
+		return fmt.Errorf("failed to upload the test report: %v", err1)
+	}
+
+	junitPath := filepath.Join(reportDir, "junit.xml")
+	if err1 = writeAutomationTestsJUnitFile(junitPath, filter, report); err1 != nil {
+		return fmt.Errorf("failed to write the JUnit report: %v", err1)
+	}
+
+	if err1 = uploadTestReportFile(job, junitPath, "application/xml", nil); err1 != nil {
+		return fmt.Errorf("failed to upload the JUnit report: %v", err1)
+	}
+
+	//endregion
+
+	if failed := report.failedTests(); len(failed) > 0 {
+		count := len(failed)
+		if count > automationTestsListLimit {
+			failed = append(failed[:automationTestsListLimit], fmt.Sprintf("... and %d more", count-automationTestsListLimit))
+		}
+		return fmt.Errorf("%d of %d automation tests failed:\n%s", count, len(report.Tests), strings.Join(failed, "\n"))
+	}
+
+	if len(report.Tests) == 0 {
+		return fmt.Errorf("no automation tests matching %s were run", filter)
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("failed to run AutomationTool: %v", runErr)
+	}
+
+	return nil
+}
+
+// loadAutomationTestReport reads the automation test report exported by the engine
+func loadAutomationTestReport(path string) (*AutomationTestReport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	// The engine writes the report with the UTF-8 byte order mark
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+
+	var report AutomationTestReport
+	if err = json.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return &report, nil
+}
+
+// failedTests returns the failed tests with their first error
+func (r *AutomationTestReport) failedTests() (failed []string) {
+	for _, t := range r.Tests {
+		if t.State != "Fail" {
+			continue
+		}
+
+		s := t.FullTestPath
+		if errors := t.errorMessages(); len(errors) > 0 {
+			s += ": " + errors[0]
+		}
+		failed = append(failed, s)
+	}
+
+	return
+}
+
+// errorMessages returns the error messages logged by the test
+func (t *AutomationTestItem) errorMessages() (messages []string) {
+	for _, e := range t.Entries {
+		if e.Event.Type == "Error" {
+			messages = append(messages, e.Event.Message)
+		}
+	}
+
+	return
+}
+
+// writeAutomationTestsJUnitFile writes the automation test report as a JUnit XML report with a test case per test
+func writeAutomationTestsJUnitFile(path string, name string, report *AutomationTestReport) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+
+	defer func(f *os.File) {
+		if err1 := f.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", path, err1)
+		}
+	}(f)
+
+	return writeAutomationTestsJUnit(f, name, report)
+}
+
+// writeAutomationTestsJUnit writes the automation test report as a JUnit XML report
+func writeAutomationTestsJUnit(w io.Writer, name string, report *AutomationTestReport) error {
+	suite := junitTestSuite{Name: name, Time: report.TotalDuration}
+
+	for _, t := range report.Tests {
+		tc := junitTestCase{Name: t.TestDisplayName, ClassName: t.FullTestPath, Time: t.Duration}
+		if i := strings.LastIndex(t.FullTestPath, "."); i > 0 {
+			tc.ClassName = t.FullTestPath[:i]
+		}
+
+		var out []string
+		for _, e := range t.Entries {
+			line := e.Event.Type + ": " + e.Event.Message
+			if e.Filename != "" {
+				line += fmt.Sprintf(" [%s:%d]", e.Filename, e.LineNumber)
+			}
+			out = append(out, line)
+		}
+
+		switch t.State {
+		case "Fail":
+			message := "Test failed"
+			if errors := t.errorMessages(); len(errors) > 0 {
+				message = errors[0]
+			}
+			tc.Failure = &junitFailure{Message: message, Type: "AutomationTest", Text: strings.Join(out, "\n")}
+			suite.Failures++
+		case "Success":
+			tc.SystemOut = strings.Join(out, "\n")
+		default:
+			tc.Skipped = &junitSkipped{Message: t.State}
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	suite.Tests = len(suite.TestCases)
+
+	result := junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(result); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// uploadTestReportFile uploads the test report to the API for storage
+func uploadTestReportFile(job JobMetadata, path string, fileMime string, params map[string]string) error {
+	Logger.Infof("uploading test report %s", path)
+
+	var fileType = "test-report"
+	if fileMime == "application/xml" {
+		fileType = "test-report-junit"
+	}
+
+	return uploadJobEntityFile(job, job.Id, fileType, fileMime, path, filepath.Base(path), params)
+}
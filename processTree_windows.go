@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// prepareProcessTree does nothing on Windows, the tree is killed by the parent process id
+func prepareProcessTree(_ *exec.Cmd) {}
+
+// killProcessTree kills the started process and all its children
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
+}
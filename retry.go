@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 2
+	defaultRetryDelay       = 30 * time.Second
+)
+
+// RetryRule classifies the AutomationTool failure as transient when an error of the failed run or one of its last output lines matches the pattern
+type RetryRule struct {
+	Name              string `json:"name"`
+	Pattern           string `json:"pattern"`                     // Regular expression matched against the formatted errors and the last output lines
+	CleanIntermediate bool   `json:"cleanIntermediate,omitempty"` // Remove the project and plugin Intermediate directories before the retry
+	Disabled          bool   `json:"disabled,omitempty"`          // Disables the default rule with the same name
+
+	regexp *regexp.Regexp
+}
+
+// RetryConfig configures the automatic retry of transient AutomationTool failures
+type RetryConfig struct {
+	MaxAttempts int         `json:"maxAttempts,omitempty"` // Maximum number of runs including the first one, default 2, 1 disables retries
+	Delay       *Duration   `json:"delay,omitempty"`       // Wait before the retry, default 30s
+	Rules       []RetryRule `json:"rules,omitempty"`       // Additional rules, rules with a default rule name replace it
+}
+
+// retryWarningRegexp matches the warning lines skipped in the output tail, e.g. "LogCook: Warning: ...", "WARNING: ..." or "A.cpp(12): warning C4996: ..."
+var retryWarningRegexp = regexp.MustCompile(`(?i)(^|\s|:)warning(\s+[A-Z]+\d+)?\s*:`)
+
+// defaultRetryRules are the known transient failures, config rules with the same name replace them
+var defaultRetryRules = []RetryRule{
+	{Name: "locked-file", Pattern: `(?i)(being used by another process|cannot access the file because|sharing violation)`},
+	{Name: "failed-to-delete", Pattern: `(?i)(failed to delete|unable to delete|could not delete)`},
+	{Name: "ddc", Pattern: `(?i)Log(DerivedDataCache|DDC|Zen)\w*:\s*(Error|Fatal):.*(timed? ?out|failed to connect|unreachable|connection (refused|reset|lost|closed|failed))`},
+	{Name: "network", Pattern: `(?i)(connection (reset|refused|timed out)|forcibly closed by the remote host|name or service not known)`},
+	{Name: "out-of-memory", Pattern: `(?i)(out of memory|ran out of memory|OutOfMemory|ShaderCompileWorker.*(crashed|terminated|exited))`},
+	{Name: "corrupt-intermediate", Pattern: `(LNK1201|C1853|LNK1136|error C1083:.*\.(pch|obj|gch))`, CleanIntermediate: true},
+}
+
+// retryRules returns the compiled default rules overridden and extended by the configured ones
+func retryRules() ([]*RetryRule, error) {
+	var rules []RetryRule
+	for _, r := range defaultRetryRules {
+		overridden := false
+		for _, c := range config.Retry.Rules {
+			if c.Name == r.Name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			rules = append(rules, r)
+		}
+	}
+	rules = append(rules, config.Retry.Rules...)
+
+	var compiled []*RetryRule
+	for i := range rules {
+		r := rules[i]
+		if r.Disabled {
+			continue
+		}
+
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry rule %s pattern: %v", r.Name, err)
+		}
+		r.regexp = re
+
+		compiled = append(compiled, &r)
+	}
+
+	return compiled, nil
+}
+
+// retryPolicy returns the maximum number of attempts and the delay between them
+func retryPolicy() (maxAttempts int, delay time.Duration) {
+	maxAttempts = defaultRetryMaxAttempts
+	if config.Retry.MaxAttempts > 0 {
+		maxAttempts = config.Retry.MaxAttempts
+	}
+
+	delay = defaultRetryDelay
+	if config.Retry.Delay != nil {
+		delay = time.Duration(*config.Retry.Delay)
+	}
+
+	return
+}
+
+// matchRetryRule returns the first rule matching the line, nil if none
+func matchRetryRule(rules []*RetryRule, line string) *RetryRule {
+	for _, r := range rules {
+		if r.regexp.MatchString(line) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// matchTransientFailure returns the first rule matching an error or one of the last output lines of the failed run, nil if none.
+// Warnings and the whole output are not matched as they mention the cache, network and file problems of runs failing for other reasons.
+func matchTransientFailure(rules []*RetryRule, entries []*LogEntry, tail []string) *RetryRule {
+	for _, e := range entries {
+		if e.Severity != LogSeverityError {
+			continue
+		}
+		if r := matchRetryRule(rules, e.String()); r != nil {
+			return r
+		}
+	}
+
+	for _, line := range tail {
+		if retryWarningRegexp.MatchString(line) {
+			continue
+		}
+		if r := matchRetryRule(rules, line); r != nil {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// jobIntermediateDirs returns the intermediate directories removed before a retry requiring a clean build
+func jobIntermediateDirs(job JobMetadata) []string {
+	if projectDir == "" {
+		return nil
+	}
+
+	dirs := []string{filepath.Join(projectDir, "Intermediate")}
+	if job.Type == "Package" && job.Package != nil && job.Package.Name != "" {
+		dirs = append(dirs, filepath.Join(projectDir, "Plugins", job.Package.Name, "Intermediate"))
+	}
+
+	return dirs
+}
+
+// cleanIntermediateDirs removes the intermediate directories
+func cleanIntermediateDirs(dirs []string) error {
+	for _, dir := range dirs {
+		Logger.Infof("removing intermediate directory %s", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", dir, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestMatchTransientFailure(t *testing.T) {
+	rules, err := retryRules()
+	if err != nil {
+		t.Fatalf("failed to get retry rules: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		lines []string
+		tail  []string
+		rule  string
+	}{
+		{
+			name:  "informational cache lines",
+			lines: []string{"LogDerivedDataCache: Display: ZenShared: Using ZenServer HTTP service at http://localhost:8558/ with connection pool", "LogZenServiceInstance: Display: Zen connection established"},
+			tail:  []string{"LogDerivedDataCache: Display: Pak: connection to the shared cache is ready", "AutomationTool exiting with ExitCode=1 (Error_Unknown)"},
+		},
+		{
+			name:  "cache error",
+			lines: []string{"[2023.01.10-12.00.00:000][  0]LogDerivedDataCache: Error: Shared DDC \\\\cache\\ddc timed out, performance may suffer"},
+			rule:  "ddc",
+		},
+		{
+			name:  "matching warnings with an unrelated error",
+			lines: []string{"LogDerivedDataCache: Warning: Shared DDC \\\\cache\\ddc timed out, performance may suffer", "WARNING: Failed to delete C:/Project/Saved/Temp/old.tmp", "LogCook: Error: Failed to load /Game/Maps/Lobby"},
+			tail:  []string{"WARNING: Failed to delete C:/Project/Saved/Temp/old.tmp", "LogDerivedDataCache: Warning: Shared DDC \\\\cache\\ddc timed out", "AutomationTool exiting with ExitCode=25 (Error_UnknownCookFailure)"},
+		},
+		{
+			name:  "zen error",
+			lines: []string{"LogZenServiceInstance: Error: Zen server failed to connect to localhost:8558"},
+			rule:  "ddc",
+		},
+		{
+			name: "locked file in the tail",
+			tail: []string{"Copying files...", "The process cannot access the file because it is being used by another process.", "AutomationTool exiting with ExitCode=1 (Error_Unknown)"},
+			rule: "locked-file",
+		},
+		{
+			name:  "linker error",
+			lines: []string{"C:/Project/Intermediate/Module.obj(1): error LNK1136: invalid or corrupt file"},
+			rule:  "corrupt-intermediate",
+		},
+		{
+			name:  "persistent error",
+			lines: []string{"LogCook: Error: Failed to load /Game/Maps/Lobby"},
+			tail:  []string{"AutomationTool exiting with ExitCode=25 (Error_UnknownCookFailure)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewUnrealLogParser()
+			for _, line := range tt.lines {
+				parser.ParseLine(line)
+			}
+
+			got := ""
+			if r := matchTransientFailure(rules, parser.Entries(), tt.tail); r != nil {
+				got = r.Name
+			}
+			if got != tt.rule {
+				t.Errorf("matched rule %q, want %q", got, tt.rule)
+			}
+		})
+	}
+}
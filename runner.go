@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// processOutputGrace is the time to wait for the output after the process exits, child processes inheriting the pipes may keep them open
+	processOutputGrace = 10 * time.Second
+
+	// processTailSize is the number of the last output lines kept for error messages
+	processTailSize = 50
+)
+
+const (
+	ProcessStreamStdout = "stdout"
+	ProcessStreamStderr = "stderr"
+)
+
+// ProcessOptions configures a run of an external tool
+type ProcessOptions struct {
+	Name              string                           // Tool name used in logs and errors, the binary name if empty
+	Path              string                           // Path to the binary, looked up in PATH if it has no directory
+	Args              []string                         // Arguments passed as is
+	Dir               string                           // Working directory, the binary directory if empty
+	Env               map[string]string                // Environment variables added to the tool environment
+	Timeout           time.Duration                    // Wall-clock timeout, disabled if zero
+	InactivityTimeout time.Duration                    // Maximum time without output, disabled if zero
+	LogPath           string                           // File the output is written to, not written if empty
+	Quiet             bool                             // Do not write the output lines to the tool log
+	OnLine            func(stream string, line string) // Receives each stdout and stderr line, never called concurrently
+	OnStart           func()                           // Called once the process has started, not called if it failed to start
+}
+
+// ProcessResult is the result of a run of an external tool
+type ProcessResult struct {
+	ExitCode int           // Exit code of the process, -1 if it did not start or was killed by a signal
+	Duration time.Duration // Time from the start to the exit
+	Tail     []string      // Last lines of the output
+	Killed   string        // Reason the process tree was killed by the runner, empty if it exited on its own
+}
+
+// runProcess runs the tool capturing stdout and stderr line by line and waits for it to exit.
+// The process tree is killed if the context is cancelled, the run exceeds the timeout or produces no output for the inactivity timeout.
+// A non-zero exit code is returned as an error along with the result.
+func runProcess(ctx context.Context, o ProcessOptions) (result ProcessResult, err error) {
+	result.ExitCode = -1
+
+	name := o.Name
+	if name == "" {
+		name = filepath.Base(o.Path)
+	}
+
+	if simulation != nil {
+		run, err := simulation.fixtureRun(name, o.Args)
+		if err != nil {
+			return result, err
+		}
+		if run != nil {
+			return replayProcess(ctx, name, o, run)
+		}
+		if simulation.record {
+			recorder := simulation.newRecorder(name, o)
+			o.OnLine = recorder.wrap(o.OnLine)
+			defer func() {
+				recorder.save(result)
+			}()
+		}
+	}
+
+	path, err := exec.LookPath(o.Path)
+	if err != nil {
+		return result, fmt.Errorf("no %s binary found at %s: %v", name, o.Path, err)
+	}
+
+	cmd := exec.Command(path, o.Args...)
+	cmd.Dir = o.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = filepath.Dir(path)
+	}
+	if len(o.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range o.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	prepareProcessTree(cmd)
+
+	// Own pipes let the process be waited for while its output is still read
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return result, fmt.Errorf("failed to create the %s stdout pipe: %v", name, err)
+	}
+	defer closeQuietly(stdoutR)
+
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		closeQuietly(stdoutW)
+		return result, fmt.Errorf("failed to create the %s stderr pipe: %v", name, err)
+	}
+	defer closeQuietly(stderrR)
+
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+
+	output := newProcessOutput(o)
+	defer output.Close()
+
+	watchdog := newProcessWatchdog(name, o.Timeout, o.InactivityTimeout)
+	output.touch = watchdog.Touch
+
+	started := time.Now()
+	err = cmd.Start()
+
+	// The process has its own copies of the write ends
+	closeQuietly(stdoutW)
+	closeQuietly(stderrW)
+
+	if err != nil {
+		return result, fmt.Errorf("failed to start %s: %v", name, err)
+	}
+
+	watchdog.Start(cmd)
+	defer watchdog.Stop()
+
+	if o.OnStart != nil {
+		o.OnStart()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go readProcessLines(stdoutR, ProcessStreamStdout, output.Line, &wg)
+	go readProcessLines(stderrR, ProcessStreamStderr, output.Line, &wg)
+
+	var cancelled string
+	var cancelledMu sync.Mutex
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelledMu.Lock()
+			cancelled = fmt.Sprintf("%s cancelled: %v", name, ctx.Err())
+			cancelledMu.Unlock()
+
+			Logger.Warningf("%s cancelled, killing the process tree", name)
+			if err := killProcessTree(cmd); err != nil {
+				Logger.Errorf("failed to kill the %s process tree: %v", name, err)
+			}
+		case <-exited:
+		}
+	}()
+
+	waitErr := cmd.Wait()
+	close(exited)
+	watchdog.Stop()
+	result.Duration = time.Since(started)
+
+	// Child processes inheriting the pipes may outlive the process, stop reading after a grace period
+	outputDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(outputDone)
+	}()
+
+	select {
+	case <-outputDone:
+	case <-time.After(processOutputGrace):
+		Logger.Warningf("%s child processes keep the output open after %s exited, closing it", name, name)
+		closeQuietly(stdoutR)
+		closeQuietly(stderrR)
+		<-outputDone
+	}
+
+	result.Tail = output.tail.Lines()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	cancelledMu.Lock()
+	result.Killed = cancelled
+	cancelledMu.Unlock()
+	if reason := watchdog.Reason(); reason != "" {
+		result.Killed = reason
+	}
+
+	if result.Killed != "" {
+		return result, fmt.Errorf("%s, last output:\n%s", result.Killed, strings.Join(lastLines(result.Tail, 10), "\n"))
+	}
+
+	if waitErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			return result, fmt.Errorf("%s exit code: %d", name, result.ExitCode)
+		}
+		return result, fmt.Errorf("failed to wait for %s: %v", name, waitErr)
+	}
+
+	return result, nil
+}
+
+// processOutput passes the tool output lines to the log file, the tool log and the line callback
+type processOutput struct {
+	mu      sync.Mutex
+	options ProcessOptions
+	tail    *lineTail
+	logFile *os.File
+	touch   func() // Called for each line, e.g. to reset the inactivity watchdog
+}
+
+// newProcessOutput creates the log file of the run if requested, the run goes on without it if it can not be created
+func newProcessOutput(o ProcessOptions) *processOutput {
+	p := &processOutput{options: o, tail: newLineTail(processTailSize)}
+
+	if o.LogPath != "" {
+		f, err := os.Create(o.LogPath)
+		if err != nil {
+			Logger.Errorf("failed to create %s: %v", o.LogPath, err)
+		} else {
+			p.logFile = f
+		}
+	}
+
+	return p
+}
+
+// Line handles a single output line, lines are handled one at a time
+func (p *processOutput) Line(stream string, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.touch != nil {
+		p.touch()
+	}
+	p.tail.Add(line)
+
+	if p.logFile != nil {
+		if _, err := p.logFile.WriteString(line + "\n"); err != nil {
+			Logger.Errorf("failed to write to %s: %v", p.options.LogPath, err)
+		}
+	}
+
+	if !p.options.Quiet {
+		Logger.Infof("%s", line)
+	}
+
+	if p.options.OnLine != nil {
+		p.options.OnLine(stream, line)
+	}
+}
+
+// Close closes the log file
+func (p *processOutput) Close() {
+	if p.logFile == nil {
+		return
+	}
+
+	if err := p.logFile.Close(); err != nil {
+		Logger.Errorf("failed to close %s: %v", p.options.LogPath, err)
+	}
+}
+
+// readProcessLines passes each line of the output to the handler until the pipe is closed
+func readProcessLines(rd io.Reader, stream string, handle func(stream string, line string), wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	reader := bufio.NewReader(rd)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			handle(stream, strings.TrimRight(line, "\r\n"))
+		}
+
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) {
+				Logger.Errorf("failed to read the %s pipe: %v", stream, err)
+			}
+			return
+		}
+	}
+}
+
+// closeQuietly closes the file ignoring the error of the already closed files
+func closeQuietly(f *os.File) {
+	if err := f.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		Logger.Errorf("failed to close %s: %v", f.Name(), err)
+	}
+}
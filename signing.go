@@ -1,79 +1,26 @@
 package main
 
 import (
-	"fmt"
-	"io"
-	"os/exec"
-	"strconv"
-	"strings"
+	"context"
 )
 
-// runSignTool runs the SignTool, logs its output to stdout and waits for it to exit completing the automation command then returns
-func runSignTool(args []string, workDir string) error {
-	cmd := exec.Command(signToolPath, args...)
-	cmd.Dir = workDir
-	rd, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to attach to the SignTool stdout pipe: %v", err)
-	}
-
-	var exitCode = -1
-
-	go func() {
-		defer func(rd io.ReadCloser) {
-			err := rd.Close()
-			if err != nil {
-				Logger.Errorf("failed to close rd: %s", err)
+// runSignTool runs the SignTool, logs its output to stdout and waits for it to exit or to time out completing the automation command then returns
+func runSignTool(job JobMetadata, args []string, workDir string, output func(line string)) error {
+	timeout, inactivity := jobTimeouts(job)
+
+	_, err := runProcess(context.Background(), ProcessOptions{
+		Name:              "SignTool",
+		Path:              signToolPath,
+		Args:              args,
+		Dir:               workDir,
+		Timeout:           timeout,
+		InactivityTimeout: inactivity,
+		OnLine: func(stream string, line string) {
+			if output != nil {
+				output(line)
 			}
-		}(rd)
-
-		b := make([]byte, 2048)
-		for {
-			nn, err := rd.Read(b)
-			if nn > 0 {
-				s := string(b[:nn])
-				Logger.Infof("%s", strings.ReplaceAll(strings.ReplaceAll(s, "\\", "/"), "\r\n", ""))
-				if strings.HasPrefix(s, "Successfully signed") {
-					exitCode = 0
-				} else if strings.Contains(s, "code") {
-					i0 := strings.Index(s, "code") + len("code")
-					i1 := i0 + 1
-					if i0 > 0 && i1 > 0 {
-						code := s[i0:i1]
-						if exitCode, err = strconv.Atoi(code); err != nil {
-							Logger.Errorf("failed to convert code %s to int: %v", code, err)
-							exitCode = -1
-						}
-					}
-				}
-			}
-			if err != nil {
-				if err == io.EOF {
-					Logger.Infof("SignTool process has exited")
-				} else {
-					Logger.Errorf("failed to read the SignTool process pipe: %v", err)
-				}
-
-				return // Exit goroutine
-			}
-		}
-	}()
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("cmd.Start() error: %v", err)
-	}
-
-	if err := cmd.Wait(); err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("cmd.Wait() error: %v", exitError)
-		} else {
-			return fmt.Errorf("cmd.Wait() error: %v", exitError)
-		}
-	}
-
-	if exitCode > 0 {
-		return fmt.Errorf("SignTool exit code: %d", exitCode)
-	}
+		},
+	})
 
-	return nil
+	return err
 }
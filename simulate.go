@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// simulationScenarioFile is the scenario description in the scenario directory
+const simulationScenarioFile = "scenario.json"
+
+// SimulationScenario is an offline end-to-end run of a job against the stand-in API with the external tools replaced by fixtures.
+// The scenario directory contains the project and launcher templates copied to the workspace, the tool fixtures and the files served by the stand-in API.
+type SimulationScenario struct {
+	Name          string                `json:"name,omitempty"`          // Scenario name, the directory name if empty
+	Job           json.RawMessage       `json:"job"`                     // Job handed out by the stand-in API, {api} is replaced with the stand-in API URL
+	ProjectName   string                `json:"projectName,omitempty"`   // Default Metaverse
+	EngineVersion string                `json:"engineVersion,omitempty"` // Engine version the project is switched to, default 5.1
+	Config        *Config               `json:"config,omitempty"`        // Tool configuration used for the job
+	Expect        SimulationExpectation `json:"expect"`
+}
+
+// SimulationExpectation is the expected outcome of the scenario job
+type SimulationExpectation struct {
+	Status  string                     `json:"status,omitempty"`  // Final job status, default completed
+	Message string                     `json:"message,omitempty"` // Regular expression matching the final status message
+	Uploads []SimulationExpectedUpload `json:"uploads,omitempty"` // Files which must be uploaded
+}
+
+// SimulationExpectedUpload is a file which must be uploaded by the scenario job
+type SimulationExpectedUpload struct {
+	Type         string `json:"type"`
+	OriginalPath string `json:"originalPath,omitempty"` // Regular expression matching the original path, any path if empty
+	Count        *int   `json:"count,omitempty"`        // Exact number of matching uploads, at least one if not set
+}
+
+// SimulationResult is the outcome of the scenario
+type SimulationResult struct {
+	Name          string          `json:"name"`
+	Passed        bool            `json:"passed"`
+	Status        string          `json:"status"`
+	Message       string          `json:"message,omitempty"`
+	Uploads       []StandInUpload `json:"uploads,omitempty"`
+	StreamedLines int             `json:"streamedLines"`
+	Failures      []string        `json:"failures,omitempty"`
+	Workspace     string          `json:"workspace,omitempty"` // Kept workspace directory
+}
+
+// newSimulateCmd creates the command running the job processing offline with the external tools replaced by fixtures
+func newSimulateCmd() *cobra.Command {
+	var (
+		record bool
+		keep   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "simulate <scenario dir>...",
+		Short: "Run jobs end-to-end against a local stand-in API with the external tools replaced by the scenario fixtures",
+		Args:  cobra.MinimumNArgs(1),
+		// Does not need the API
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {},
+		RunE: func(_ *cobra.Command, args []string) error {
+			var results []SimulationResult
+			failed := 0
+			for _, dir := range args {
+				result, err := runSimulationScenario(dir, record, keep)
+				if err != nil {
+					return fmt.Errorf("failed to run scenario %s: %v", dir, err)
+				}
+
+				if !result.Passed {
+					failed++
+				}
+				results = append(results, result)
+			}
+
+			for _, r := range results {
+				if r.Passed {
+					fmt.Printf("PASS %s: %s, %d uploads, %d streamed lines\n", r.Name, r.Status, len(r.Uploads), r.StreamedLines)
+				} else {
+					fmt.Printf("FAIL %s: %s\n", r.Name, strings.Join(r.Failures, "; "))
+				}
+				if r.Workspace != "" {
+					fmt.Printf("     workspace %s\n", r.Workspace)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d scenarios failed", failed, len(results))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&record, "record", false, "run the tools without fixtures for real and record their runs as fixtures, tool paths are read from the VAT_*_PATH variables")
+	cmd.Flags().BoolVar(&keep, "keep", false, "keep the workspace directory with the project, the job logs and the metrics")
+
+	return cmd
+}
+
+// runSimulationScenario runs the job of the scenario directory in a temporary workspace and checks the expectations
+func runSimulationScenario(dir string, record bool, keep bool) (result SimulationResult, err error) {
+	// The job runs in the workspace directory
+	if dir, err = filepath.Abs(dir); err != nil {
+		return result, fmt.Errorf("failed to get the scenario directory: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, simulationScenarioFile))
+	if err != nil {
+		return result, fmt.Errorf("failed to read the scenario: %v", err)
+	}
+
+	var scenario SimulationScenario
+	if err = json.Unmarshal(b, &scenario); err != nil {
+		return result, fmt.Errorf("failed to parse the scenario: %v", err)
+	}
+
+	result.Name = scenario.Name
+	if result.Name == "" {
+		result.Name = filepath.Base(filepath.Clean(dir))
+	}
+
+	workspace, err := os.MkdirTemp("", "vat-simulation-")
+	if err != nil {
+		return result, fmt.Errorf("failed to create the workspace: %v", err)
+	}
+
+	if keep {
+		result.Workspace = workspace
+	} else {
+		defer func() {
+			if err := os.RemoveAll(workspace); err != nil {
+				Logger.Errorf("failed to remove the workspace %s: %v", workspace, err)
+			}
+		}()
+	}
+
+	// Files the job writes to the working directory, e.g. the release archive, are kept in the workspace
+	wd, err := os.Getwd()
+	if err != nil {
+		return result, fmt.Errorf("failed to get the working directory: %v", err)
+	}
+	if err = os.Chdir(workspace); err != nil {
+		return result, fmt.Errorf("failed to change the working directory to the workspace: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			Logger.Errorf("failed to restore the working directory %s: %v", wd, err)
+		}
+	}()
+
+	api := startStandInApi(filepath.Join(dir, "files"))
+	defer api.Close()
+
+	var job JobMetadata
+	if err = json.Unmarshal(bytes.ReplaceAll(scenario.Job, []byte("{api}"), []byte(api.Url())), &job); err != nil {
+		return result, fmt.Errorf("failed to parse the scenario job: %v", err)
+	}
+	api.jobs = append(api.jobs, job)
+
+	if err = prepareSimulationWorkspace(dir, workspace, scenario, job); err != nil {
+		return result, err
+	}
+
+	simulation = newProcessSimulation(filepath.Join(dir, "tools"), record, simulationVars(job))
+	defer func() {
+		simulation = nil
+	}()
+
+	api2Url = api.Url()
+	if token, err = login(); err != nil {
+		return result, fmt.Errorf("failed to login to the stand-in API: %v", err)
+	}
+
+	if err = process(); err != nil {
+		Logger.Warningf("simulated job failed: %v", err)
+	}
+
+	status := api.LastStatus()
+	result.Status = status.Status
+	result.Message = status.Message
+	result.Uploads = api.Uploads
+	result.StreamedLines = api.StreamedLines
+	result.Failures = scenario.Expect.check(result)
+	result.Passed = len(result.Failures) == 0
+
+	return result, nil
+}
+
+// prepareSimulationWorkspace copies the project and launcher templates to the workspace and points the tool at them
+func prepareSimulationWorkspace(dir string, workspace string, scenario SimulationScenario, job JobMetadata) error {
+	projectName = scenario.ProjectName
+	if projectName == "" {
+		projectName = "Metaverse"
+	}
+
+	ueVersionCode = scenario.EngineVersion
+	if ueVersionCode == "" {
+		ueVersionCode = "5.1"
+	}
+	ueVersionMarketplace = ueVersionCode
+
+	projectDir = filepath.Join(workspace, "project")
+	launcherDir = filepath.Join(workspace, "launcher")
+	for name, target := range map[string]string{"project": projectDir, "launcher": launcherDir} {
+		template := filepath.Join(dir, name)
+		if _, err := os.Stat(template); errors.Is(err, os.ErrNotExist) {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %v", target, err)
+			}
+			continue
+		}
+
+		if err := copyDir(template, target); err != nil {
+			return fmt.Errorf("failed to copy the %s template: %v", name, err)
+		}
+	}
+
+	// Client releases check out the code version tag
+	if job.Release != nil && job.Release.CodeVersion != "" {
+		if err := initSimulationRepo(projectDir, job.Release.CodeVersion); err != nil {
+			return fmt.Errorf("failed to create the project repo: %v", err)
+		}
+	}
+
+	// Real tools are only used when recording
+	uatPath = simulationToolPath("VAT_UAT_PATH", "AutomationTool")
+	editorPath = simulationToolPath("VAT_EDITOR_PATH", "UnrealEditor-Cmd")
+	wailsPath = simulationToolPath("VAT_WAILS_PATH", "wails")
+	signToolPath = simulationToolPath("VAT_SIGNTOOL_PATH", "signtool")
+	certFile, certPassword = "", ""
+
+	// Only the scenario job is supported, jobs of the previous scenarios are not
+	platforms, jobTypes, deployments = job.Platform, job.Type, job.Deployment
+	supportedPlatforms = map[string]bool{job.Platform: true}
+	supportedJobTypes = map[string]bool{job.Type: true}
+	supportedDeployments = map[string]bool{job.Deployment: true}
+
+	if scenario.Config != nil {
+		config = *scenario.Config
+	} else {
+		config = Config{}
+	}
+
+	// Keep the job logs, metrics and baselines in the workspace
+	config.LogArchive.Dir = filepath.Join(workspace, "job-logs")
+	config.MetricsPath = filepath.Join(workspace, "job-metrics.jsonl")
+	config.QualityGate.BaselineDir = filepath.Join(workspace, "job-baselines")
+	if config.Retry.Delay == nil {
+		noDelay := Duration(0)
+		config.Retry.Delay = &noDelay
+	}
+
+	if _, err := retryRules(); err != nil {
+		return fmt.Errorf("invalid scenario configuration: %v", err)
+	}
+
+	if err := loadEngineRegistry(config.Engines); err != nil {
+		return fmt.Errorf("invalid scenario configuration: %v", err)
+	}
+	setDefaultEngine()
+
+	return nil
+}
+
+// simulationToolPath returns the tool path from the environment variable, the tool name if not set
+func simulationToolPath(env string, name string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+
+	return name
+}
+
+// simulationVars returns the placeholder values of the fixture file paths for the job
+func simulationVars(job JobMetadata) map[string]string {
+	vars := map[string]string{
+		"projectDir":    filepath.ToSlash(projectDir),
+		"projectName":   projectName,
+		"launcherDir":   filepath.ToSlash(launcherDir),
+		"platform":      job.Platform,
+		"platformName":  getPlatformName(job),
+		"configuration": job.Configuration,
+		"deployment":    job.Deployment,
+	}
+
+	if job.Package != nil {
+		vars["packageName"] = job.Package.Name
+	}
+	if job.App != nil {
+		vars["appName"] = job.App.Name
+	}
+	if job.Release != nil {
+		vars["appName"] = job.Release.AppName
+		vars["version"] = job.Release.Version
+	}
+
+	return vars
+}
+
+// initSimulationRepo commits the project template to a new repo tagged with the code version
+func initSimulationRepo(dir string, tag string) error {
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err = w.AddGlob("."); err != nil {
+		return err
+	}
+
+	signature := &object.Signature{Name: "simulation", Email: "simulation@localhost", When: time.Now()}
+	hash, err := w.Commit("Project template", &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.CreateTag(tag, hash, nil)
+	return err
+}
+
+// check returns the unmet expectations
+func (e SimulationExpectation) check(result SimulationResult) (failures []string) {
+	status := e.Status
+	if status == "" {
+		status = supportedJobStatuses[JobStatusCompleted]
+	}
+
+	if result.Status != status {
+		failures = append(failures, fmt.Sprintf("expected status %s, got %s: %s", status, result.Status, result.Message))
+	}
+
+	if e.Message != "" {
+		if m, err := regexp.MatchString(e.Message, result.Message); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid expected message: %v", err))
+		} else if !m {
+			failures = append(failures, fmt.Sprintf("expected message matching %s, got %s", e.Message, result.Message))
+		}
+	}
+
+	for _, u := range e.Uploads {
+		pattern, err := regexp.Compile(u.OriginalPath)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid expected upload path %s: %v", u.OriginalPath, err))
+			continue
+		}
+
+		count := 0
+		for _, upload := range result.Uploads {
+			if upload.Type == u.Type && pattern.MatchString(upload.OriginalPath) {
+				count++
+			}
+		}
+
+		if (u.Count == nil && count == 0) || (u.Count != nil && count != *u.Count) {
+			expected := "at least 1"
+			if u.Count != nil {
+				expected = fmt.Sprint(*u.Count)
+			}
+			failures = append(failures, fmt.Sprintf("expected %s %s uploads matching %q, got %d", expected, u.Type, u.OriginalPath, count))
+		}
+	}
+
+	return
+}
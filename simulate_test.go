@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimulationScenarios(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("simulations", "*", simulationScenarioFile))
+	if err != nil {
+		t.Fatalf("failed to list the scenarios: %v", err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("no scenarios found")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get the working directory: %v", err)
+	}
+
+	for _, path := range dirs {
+		dir := filepath.Dir(path)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			result, err := runSimulationScenario(dir, false, false)
+			if err != nil {
+				t.Fatalf("failed to run the scenario: %v", err)
+			}
+			if !result.Passed {
+				t.Errorf("scenario failed: %s", strings.Join(result.Failures, "; "))
+			}
+
+			if current, err := os.Getwd(); err != nil || current != wd {
+				t.Errorf("working directory changed to %s: %v", current, err)
+			}
+		})
+	}
+
+	// Nothing the jobs write to the working directory is left in the repo
+	leftovers, err := filepath.Glob("*.zip")
+	if err != nil {
+		t.Fatalf("failed to list the archives: %v", err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("scenarios left files in the working directory: %s", strings.Join(leftovers, ", "))
+	}
+}
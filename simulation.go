@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ToolFixture scripts the runs of an external tool replaced by a fake in the simulation mode, stored as <tool name>.json in the fixtures directory
+type ToolFixture struct {
+	Tool string            `json:"tool"`
+	Runs []*ToolFixtureRun `json:"runs"`
+}
+
+// ToolFixtureRun is a single scripted run of the tool
+type ToolFixtureRun struct {
+	Match    string            `json:"match,omitempty"`    // Regular expression matched against the space-joined arguments, matches any run if empty
+	Stdout   []string          `json:"stdout,omitempty"`   // Output lines replayed on stdout
+	Stderr   []string          `json:"stderr,omitempty"`   // Output lines replayed on stderr after stdout
+	Log      string            `json:"log,omitempty"`      // Recorded output file relative to the fixtures directory, replayed on stdout after the lines
+	ExitCode int               `json:"exitCode"`           // Exit code of the fake
+	Files    []ToolFixtureFile `json:"files,omitempty"`    // Files generated by the run
+	Duration *Duration         `json:"duration,omitempty"` // Time the fake takes to run, e.g. to trigger timeouts
+
+	regexp *regexp.Regexp
+	runs   int // Number of times the run has been replayed
+}
+
+// ToolFixtureFile is a file generated by a scripted run
+type ToolFixtureFile struct {
+	Path    string `json:"path"`              // Path with placeholders such as {projectDir}, {args.platform} or {packageName}, relative to the working directory of the run
+	Content string `json:"content,omitempty"` // Text content of the file
+	Size    int64  `json:"size,omitempty"`    // Size of the generated content used if there is no text content
+}
+
+// processSimulation replaces the external tools having fixtures with scripted fakes and records the runs of the other tools
+type processSimulation struct {
+	dir    string            // Fixtures directory
+	record bool              // Record the runs of the tools without fixtures as new fixtures
+	vars   map[string]string // Placeholder values of the fixture file paths
+
+	mu       sync.Mutex
+	fixtures map[string]*ToolFixture // Loaded fixtures by the tool name, nil if the tool has no fixture
+}
+
+// simulation is the active simulation, external tools run for real if nil
+var simulation *processSimulation
+
+// newProcessSimulation creates the simulation replaying the fixtures of the directory
+func newProcessSimulation(dir string, record bool, vars map[string]string) *processSimulation {
+	return &processSimulation{dir: dir, record: record, vars: vars, fixtures: map[string]*ToolFixture{}}
+}
+
+// fixture loads the fixture of the tool, nil if the tool has none
+func (s *processSimulation) fixture(name string) (*ToolFixture, error) {
+	if f, ok := s.fixtures[name]; ok {
+		return f, nil
+	}
+
+	path := filepath.Join(s.dir, name+".json")
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.fixtures[name] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the %s fixture: %v", name, err)
+	}
+
+	var f ToolFixture
+	if err = json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s fixture %s: %v", name, path, err)
+	}
+
+	for i, r := range f.Runs {
+		if r.regexp, err = regexp.Compile(r.Match); err != nil {
+			return nil, fmt.Errorf("invalid match of the %s fixture run %d: %v", name, i+1, err)
+		}
+	}
+
+	s.fixtures[name] = &f
+
+	return &f, nil
+}
+
+// fixtureRun returns the first matching run of the tool fixture which has not been replayed yet, or the last matching one.
+// Returns nil if the tool has no fixture, a tool with a fixture but no matching run fails.
+func (s *processSimulation) fixtureRun(name string, args []string) (*ToolFixtureRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.fixture(name)
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	commandLine := strings.Join(args, " ")
+
+	var last *ToolFixtureRun
+	for _, r := range f.Runs {
+		if !r.regexp.MatchString(commandLine) {
+			continue
+		}
+		if r.runs == 0 {
+			last = r
+			break
+		}
+		last = r
+	}
+
+	if last == nil {
+		return nil, fmt.Errorf("no %s fixture run matches the arguments: %s", name, commandLine)
+	}
+
+	last.runs++
+
+	return last, nil
+}
+
+// replayProcess replays the scripted run through the same output handling as the real tool run
+func replayProcess(ctx context.Context, name string, o ProcessOptions, run *ToolFixtureRun) (result ProcessResult, err error) {
+	result.ExitCode = -1
+
+	Logger.Infof("simulating %s %s", name, strings.Join(o.Args, " "))
+
+	output := newProcessOutput(o)
+	defer output.Close()
+
+	started := time.Now()
+	if o.OnStart != nil {
+		o.OnStart()
+	}
+
+	lines := func(stream string, lines []string) {
+		for _, line := range lines {
+			if ctx.Err() != nil {
+				return
+			}
+			output.Line(stream, line)
+		}
+	}
+
+	lines(ProcessStreamStdout, run.Stdout)
+	lines(ProcessStreamStderr, run.Stderr)
+
+	if run.Log != "" {
+		if err = replayLog(ctx, filepath.Join(simulation.dir, run.Log), output); err != nil {
+			return result, err
+		}
+	}
+
+	if run.Duration != nil {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(*run.Duration)):
+		}
+	}
+
+	if o.Timeout > 0 && time.Since(started) > o.Timeout {
+		result.Killed = fmt.Sprintf("%s timed out after %s", name, o.Timeout)
+	} else if ctx.Err() != nil {
+		result.Killed = fmt.Sprintf("%s cancelled: %v", name, ctx.Err())
+	}
+
+	result.Duration = time.Since(started)
+	result.Tail = output.tail.Lines()
+
+	if result.Killed != "" {
+		return result, fmt.Errorf("%s, last output:\n%s", result.Killed, strings.Join(lastLines(result.Tail, 10), "\n"))
+	}
+
+	workDir := o.Dir
+	if workDir == "" {
+		workDir = filepath.Dir(o.Path)
+	}
+
+	for _, f := range run.Files {
+		if err = writeFixtureFile(f, workDir, simulation.fixtureVars(workDir, o.Args)); err != nil {
+			return result, err
+		}
+	}
+
+	result.ExitCode = run.ExitCode
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("%s exit code: %d", name, result.ExitCode)
+	}
+
+	return result, nil
+}
+
+// replayLog replays the recorded output file line by line
+func replayLog(ctx context.Context, path string, output *processOutput) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the recorded output %s: %v", path, err)
+	}
+
+	defer func(f *os.File) {
+		if err1 := f.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %v", path, err1)
+		}
+	}(f)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() && ctx.Err() == nil {
+		output.Line(ProcessStreamStdout, scanner.Text())
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the recorded output %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// fixtureVars returns the placeholder values for the run, the simulation values along with {workDir} and {args.<name>} for each -name=value or -name value argument
+func (s *processSimulation) fixtureVars(workDir string, args []string) map[string]string {
+	vars := map[string]string{"workDir": workDir}
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if k, v, ok := strings.Cut(name, "="); ok {
+			vars["args."+strings.ToLower(k)] = v
+		} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			vars["args."+strings.ToLower(name)] = args[i+1]
+		}
+	}
+
+	return vars
+}
+
+// writeFixtureFile writes the file generated by the scripted run
+func writeFixtureFile(f ToolFixtureFile, workDir string, vars map[string]string) error {
+	path := filepath.FromSlash(expand(vars, f.Path))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create a directory for the fixture file %s: %v", path, err)
+	}
+
+	content := []byte(f.Content)
+	if len(content) == 0 && f.Size > 0 {
+		content = make([]byte, f.Size)
+	}
+
+	if err := os.WriteFile(path, content, 0755); err != nil {
+		return fmt.Errorf("failed to write the fixture file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// recordedFileContentLimit is the maximum size of the generated text files recorded with their content, larger and binary files are recorded with their size
+const recordedFileContentLimit = 64 * 1024
+
+// toolRecorder records the output, the exit code and the generated files of a real tool run as a new fixture run
+type toolRecorder struct {
+	simulation *processSimulation
+	name       string
+	args       []string
+	lines      []string
+	workDir    string
+	logPath    string
+	before     map[string]time.Time // Modification times of the files before the run
+}
+
+// newRecorder creates the recorder of the tool run and takes the snapshot of the files the run may generate
+func (s *processSimulation) newRecorder(name string, o ProcessOptions) *toolRecorder {
+	r := &toolRecorder{simulation: s, name: name, args: o.Args, workDir: o.Dir, logPath: o.LogPath}
+	if r.workDir == "" {
+		if path, err := exec.LookPath(o.Path); err == nil {
+			r.workDir = filepath.Dir(path)
+		}
+	}
+
+	r.before = r.snapshot()
+
+	return r
+}
+
+// roots returns the directories searched for the generated files, the project, the launcher and the working directory of the run
+func (r *toolRecorder) roots() (roots []string) {
+	for _, dir := range []string{projectDir, launcherDir, r.workDir} {
+		if dir == "" {
+			continue
+		}
+
+		nested := false
+		for _, root := range roots {
+			if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			roots = append(roots, dir)
+		}
+	}
+
+	return
+}
+
+// snapshot returns the modification times of the files in the roots
+func (r *toolRecorder) snapshot() map[string]time.Time {
+	files := map[string]time.Time{}
+	for _, root := range r.roots() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files[path] = info.ModTime()
+
+			return nil
+		})
+		if err != nil {
+			Logger.Warningf("failed to list the files of %s, generated files may not be recorded: %v", root, err)
+		}
+	}
+
+	return files
+}
+
+// generatedFiles returns the files created or modified by the run, paths in the project and the launcher use their placeholders
+func (r *toolRecorder) generatedFiles() (files []ToolFixtureFile) {
+	after := r.snapshot()
+
+	var paths []string
+	for path, modTime := range after {
+		if before, ok := r.before[path]; (!ok || !before.Equal(modTime)) && path != r.logPath {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			Logger.Errorf("failed to read the generated file %s, not recording: %v", path, err)
+			continue
+		}
+
+		f := ToolFixtureFile{Path: r.fixturePath(path)}
+		if len(b) <= recordedFileContentLimit && utf8.Valid(b) {
+			f.Content = string(b)
+		} else {
+			f.Size = int64(len(b))
+		}
+		files = append(files, f)
+	}
+
+	return
+}
+
+// fixturePath returns the fixture path of the generated file, relative to the project, the launcher or the working directory of the run
+func (r *toolRecorder) fixturePath(path string) string {
+	for _, root := range []struct{ dir, placeholder string }{{projectDir, "{projectDir}/"}, {launcherDir, "{launcherDir}/"}, {r.workDir, ""}} {
+		if root.dir == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(root.dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return root.placeholder + filepath.ToSlash(rel)
+		}
+	}
+
+	return filepath.ToSlash(path)
+}
+
+// wrap returns the line callback recording the lines before passing them to the original callback
+func (r *toolRecorder) wrap(onLine func(stream string, line string)) func(stream string, line string) {
+	return func(stream string, line string) {
+		r.lines = append(r.lines, line)
+		if onLine != nil {
+			onLine(stream, line)
+		}
+	}
+}
+
+// save appends the run to the tool fixture with the files it generated, the output is stored next to it as <tool name>-NN.log
+func (r *toolRecorder) save(result ProcessResult) {
+	s := r.simulation
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := filepath.Join(s.dir, r.name+".json")
+
+	f := &ToolFixture{Tool: r.name}
+	if b, err := os.ReadFile(path); err == nil {
+		if err = json.Unmarshal(b, f); err != nil {
+			Logger.Errorf("failed to parse the %s fixture %s, not recording: %v", r.name, path, err)
+			return
+		}
+	}
+
+	logName := fmt.Sprintf("%s-%02d.log", r.name, len(f.Runs)+1)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		Logger.Errorf("failed to create the fixtures directory %s: %v", s.dir, err)
+		return
+	}
+
+	if err := os.WriteFile(filepath.Join(s.dir, logName), []byte(strings.Join(r.lines, "\n")+"\n"), 0644); err != nil {
+		Logger.Errorf("failed to write the recorded output %s: %v", logName, err)
+		return
+	}
+
+	run := &ToolFixtureRun{Log: logName, ExitCode: result.ExitCode, Files: r.generatedFiles()}
+	if len(r.args) > 0 {
+		// The command is stable across machines unlike the paths in the other arguments
+		run.Match = regexp.QuoteMeta(r.args[0])
+	}
+	f.Runs = append(f.Runs, run)
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		Logger.Errorf("failed to serialize the %s fixture: %v", r.name, err)
+		return
+	}
+
+	if err = os.WriteFile(path, b, 0644); err != nil {
+		Logger.Errorf("failed to write the %s fixture %s: %v", r.name, path, err)
+		return
+	}
+
+	Logger.Infof("recorded %s run to %s", r.name, path)
+}
@@ -0,0 +1 @@
+#include "Forest.h"
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	goRuntime "runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSmokeTestReadyPattern matches the server log lines printed once the map is loaded and the server accepts connections
+	defaultSmokeTestReadyPattern = `(?i)(IpNetDriver.*listening on port|GameNetDriver .* listening|Bringing up level for play took)`
+	defaultSmokeTestTimeout      = 5 * time.Minute
+)
+
+// SmokeTestConfig configures the verification of staged server releases by launching them headless
+type SmokeTestConfig struct {
+	Enabled      bool      `json:"enabled,omitempty"`
+	ReadyPattern string    `json:"readyPattern,omitempty"` // Regular expression of the output line telling the server is ready
+	Port         int       `json:"port,omitempty"`         // The server is also ready once it accepts TCP connections at the port, disabled if zero
+	Timeout      *Duration `json:"timeout,omitempty"`      // Maximum time to wait for the server to get ready, default 5m
+	Args         []string  `json:"args,omitempty"`         // Additional server arguments
+}
+
+// smokeTestServerRelease launches the staged server with the release map headless and waits for it to get ready, then shuts it down.
+// Skipped if disabled or if the release platform can not run on this machine.
+func smokeTestServerRelease(job JobMetadata, stagingDir string) error {
+	if !config.SmokeTest.Enabled {
+		return nil
+	}
+
+	if !isHostPlatform(job.Platform) {
+		Logger.Infof("skipping server smoke test, %s servers can not run on %s", job.Platform, goRuntime.GOOS)
+		return nil
+	}
+
+	binary, err := findStagedServerBinary(stagingDir, job.Platform)
+	if err != nil {
+		return fmt.Errorf("failed to find the staged server: %v", err)
+	}
+
+	pattern := config.SmokeTest.ReadyPattern
+	if pattern == "" {
+		pattern = defaultSmokeTestReadyPattern
+	}
+
+	ready, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid smoke test ready pattern: %v", err)
+	}
+
+	timeout := defaultSmokeTestTimeout
+	if config.SmokeTest.Timeout != nil {
+		timeout = time.Duration(*config.SmokeTest.Timeout)
+	}
+
+	var args []string
+	if job.Release.Map != "" {
+		args = append(args, job.Release.Map)
+	}
+	args = append(args, "-server", "-log", "-stdout", "-FullStdOutLogOutput", "-nullrhi", "-unattended", "-nosound", "-nosplash")
+	if config.SmokeTest.Port > 0 {
+		args = append(args, fmt.Sprintf("-port=%d", config.SmokeTest.Port))
+	}
+	args = append(args, config.SmokeTest.Args...)
+
+	Logger.Infof("smoke testing server %s %s", binary, strings.Join(args, " "))
+
+	// The server is stopped by cancelling the run once it is ready
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var readyMu sync.Mutex
+	var readyReason string
+	setReady := func(reason string) {
+		readyMu.Lock()
+		defer readyMu.Unlock()
+		if readyReason == "" {
+			readyReason = reason
+			cancel()
+		}
+	}
+
+	// A server left running by an earlier job would be taken for the tested one
+	if config.SmokeTest.Port > 0 {
+		if err = checkSmokeTestPortFree(config.SmokeTest.Port); err != nil {
+			return err
+		}
+	}
+
+	// The port is probed only once the server has started
+	probePort := func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", config.SmokeTest.Port), time.Second)
+				if err == nil {
+					_ = conn.Close()
+					setReady(fmt.Sprintf("server is listening on port %d", config.SmokeTest.Port))
+					return
+				}
+			}
+		}
+	}
+
+	output := jobLogWriter(job, "server")
+	result, _ := runProcess(ctx, ProcessOptions{
+		Name:    "server",
+		Path:    binary,
+		Args:    args,
+		Timeout: timeout,
+		LogPath: jobToolLogPath(job, "server"),
+		Quiet:   true,
+		OnStart: func() {
+			if config.SmokeTest.Port > 0 {
+				go probePort()
+			}
+		},
+		OnLine: func(stream string, line string) {
+			if output != nil {
+				output(line)
+			}
+			if ready.MatchString(line) {
+				setReady("server is ready: " + strings.TrimSpace(line))
+			}
+		},
+	})
+
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	if readyReason != "" {
+		Logger.Infof("%s", readyReason)
+		return nil
+	}
+
+	if result.Killed != "" {
+		return fmt.Errorf("server did not get ready in %s, last output:\n%s", timeout, strings.Join(lastLines(result.Tail, 20), "\n"))
+	}
+
+	return fmt.Errorf("server exited with code %d before getting ready, last output:\n%s", result.ExitCode, strings.Join(lastLines(result.Tail, 20), "\n"))
+}
+
+// checkSmokeTestPortFree returns an error if something already listens at the smoke test port
+func checkSmokeTestPortFree(port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("smoke test port %d is already in use: %v", port, err)
+	}
+
+	return l.Close()
+}
+
+// isHostPlatform reports whether binaries of the platform run on this machine
+func isHostPlatform(platform string) bool {
+	switch platform {
+	case "Linux":
+		return goRuntime.GOOS == "linux"
+	case "Win64":
+		return goRuntime.GOOS == "windows"
+	case "Mac":
+		return goRuntime.GOOS == "darwin"
+	}
+
+	return false
+}
+
+// findStagedServerBinary returns the launch script or the server binary of the staged server
+func findStagedServerBinary(stagingDir string, platform string) (string, error) {
+	serverName := projectName + "Server"
+
+	// Staging creates a launcher next to the project directory
+	for _, name := range []string{serverName + ".sh", serverName + ".exe", serverName} {
+		path := filepath.Join(stagingDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+
+	binariesDir := filepath.Join(stagingDir, projectName, "Binaries", platform)
+	entries, err := os.ReadDir(binariesDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", binariesDir, err)
+	}
+
+	// Server binaries are named e.g. MetaverseServer-Linux-Shipping, skip debug files such as .debug, .sym and .pdb
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if !e.IsDir() && strings.HasPrefix(e.Name(), serverName) && (ext == "" || ext == ".exe") {
+			return filepath.Join(binariesDir, e.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s binary found in %s", serverName, stagingDir)
+}
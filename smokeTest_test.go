@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	goRuntime "runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindStagedServerBinary(t *testing.T) {
+	oldProjectName := projectName
+	t.Cleanup(func() {
+		projectName = oldProjectName
+	})
+	projectName = "Metaverse"
+
+	tests := []struct {
+		name  string
+		files []string
+		want  string // Empty if no binary is found
+	}{
+		{"launch script", []string{"MetaverseServer.sh", "Metaverse/Binaries/Linux/MetaverseServer-Linux-Shipping"}, "MetaverseServer.sh"},
+		{"windows launcher", []string{"MetaverseServer.exe"}, "MetaverseServer.exe"},
+		{"binary", []string{"Metaverse/Binaries/Linux/MetaverseServer-Linux-Shipping.debug", "Metaverse/Binaries/Linux/MetaverseServer-Linux-Shipping.sym", "Metaverse/Binaries/Linux/MetaverseServer-Linux-Shipping"}, "Metaverse/Binaries/Linux/MetaverseServer-Linux-Shipping"},
+		{"client only", []string{"Metaverse.sh", "Metaverse/Binaries/Linux/Metaverse-Linux-Shipping"}, ""},
+		{"no binaries", []string{"Manifest_NonUFSFiles_Linux.txt"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create directory: %v", err)
+				}
+				if err := os.WriteFile(path, nil, 0755); err != nil {
+					t.Fatalf("failed to create file: %v", err)
+				}
+			}
+
+			got, err := findStagedServerBinary(dir, "Linux")
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("findStagedServerBinary() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findStagedServerBinary() failed: %v", err)
+			}
+			if want := filepath.Join(dir, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("findStagedServerBinary() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSmokeTestServerRelease(t *testing.T) {
+	if goRuntime.GOOS != "linux" {
+		t.Skip("the fake server is a shell script")
+	}
+
+	oldProjectName, oldConfig := projectName, config
+	t.Cleanup(func() {
+		projectName, config = oldProjectName, oldConfig
+	})
+	projectName = "Metaverse"
+
+	// The server log is written to the working directory without a job log archive
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get the working directory: %v", err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change the working directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer busy.Close()
+
+	timeout := Duration(2 * time.Second)
+
+	tests := []struct {
+		name   string
+		script string
+		port   int
+		err    string // Expected error message part, empty if the server gets ready
+	}{
+		{
+			name:   "ready",
+			script: "echo 'LogInit: Display: Starting'\necho 'LogNet: IpNetDriver_0 listening on port 7777'\nexec sleep 30\n",
+		},
+		{
+			name:   "timeout",
+			script: "echo 'LogInit: Display: Starting'\nexec sleep 30\n",
+			err:    "did not get ready in 2s",
+		},
+		{
+			name:   "exit",
+			script: "echo 'LogWindows: Error: Assertion failed'\nexit 3\n",
+			err:    "exited with code 3",
+		},
+		{
+			name:   "port in use",
+			script: "echo 'LogNet: IpNetDriver_0 listening on port 7777'\nexec sleep 30\n",
+			port:   busy.Addr().(*net.TCPAddr).Port,
+			err:    "already in use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "MetaverseServer.sh"), []byte("#!/bin/sh\n"+tt.script), 0755); err != nil {
+				t.Fatalf("failed to create the fake server: %v", err)
+			}
+
+			config = Config{SmokeTest: SmokeTestConfig{Enabled: true, Port: tt.port, Timeout: &timeout}}
+
+			err := smokeTestServerRelease(JobMetadata{Platform: "Linux", Release: &Release{Map: "/Game/Maps/Lobby"}}, dir)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("smokeTestServerRelease() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("smokeTestServerRelease() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
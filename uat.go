@@ -1,188 +1,178 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"context"
 	"fmt"
-	"io"
-	"log"
-	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
 // UnrealAutomationToolResult is the result of running the Unreal Automation Tool
 type UnrealAutomationToolResult struct {
 	ExitCode int
-	Warnings []string
-	Errors   []string
+	Warnings []string       // Formatted warnings, identical warnings are merged
+	Errors   []string       // Formatted errors, identical errors are merged
+	Entries  []*LogEntry    // Structured warnings and errors
+	Tail     []string       // Last lines of the output
+	Metrics  *BuildMetrics  // Phase durations and counters
+	Attempts int            // Number of runs including retries
+	Retry    *RetryRule     // Transient failure rule matching the errors or the last output lines of the failed run, nil if none
+	Crashes  []*CrashReport // Crashes and failed assertions of the failed run
 }
 
-// Process Unreal Automation Tool result
-func (r *UnrealAutomationToolResult) Process() {
-	if r.ExitCode != 0 {
-		for k, e := range r.Errors {
-			pattern := "^Log(\\w+)\\:\\s?(Error)\\:\\s?(.+)$"
+// UnrealAutomationToolOptions are the options of the Unreal Automation Tool run
+type UnrealAutomationToolOptions struct {
+	Context           context.Context                                            // Cancels the run killing the process tree, never cancelled if nil
+	Env               map[string]string                                          // Additional environment variables
+	Timeout           time.Duration                                              // Wall-clock timeout, disabled if zero
+	InactivityTimeout time.Duration                                              // Maximum time without output, disabled if zero
+	Output            func(line string)                                          // Receives each output line, e.g. to stream it to the API
+	LogPath           string                                                     // File the output is written to, uat.log if empty
+	Report            func(run *JobRunReport, result UnrealAutomationToolResult) // Receives the run report and the result when each attempt ends
+	RetryRules        []*RetryRule                                               // Rules classifying failures as transient
+	MaxAttempts       int                                                        // Maximum number of runs, failures are not retried if less than 2
+	RetryDelay        time.Duration                                              // Wait before the retry
+	IntermediateDirs  []string                                                   // Directories removed before a retry by the rules requiring a clean build
+	CrashDirs         []string                                                   // Saved/Crashes directories searched for the crash folders of the failed run
+}
 
-			reg, err := regexp.Compile(pattern)
-			if err != nil {
-				log.Printf("failed to compile regex: %s", err)
-			}
+// newUnrealAutomationToolOptions returns the run options for the job with the configured timeouts and retry policy
+func newUnrealAutomationToolOptions(job JobMetadata, env map[string]string) UnrealAutomationToolOptions {
+	timeout, inactivity := jobTimeouts(job)
+	maxAttempts, retryDelay := retryPolicy()
+
+	rules, err := retryRules()
+	if err != nil {
+		// Validated when the configuration is loaded
+		Logger.Errorf("failed to get retry rules: %v", err)
+	}
 
-			matches := reg.FindStringSubmatch(e)
+	return UnrealAutomationToolOptions{
+		Env:               env,
+		Timeout:           timeout,
+		InactivityTimeout: inactivity,
+		Output:            jobLogWriter(job, "uat"),
+		LogPath:           jobToolLogPath(job, "uat"),
+		Report:            jobRunReporter(job),
+		RetryRules:        rules,
+		MaxAttempts:       maxAttempts,
+		RetryDelay:        retryDelay,
+		IntermediateDirs:  jobIntermediateDirs(job),
+		CrashDirs:         jobCrashDirs(job),
+	}
+}
 
-			if len(matches) == 4 {
-				r.Errors[k] = fmt.Sprintf("Category: %s\nSeverity: %s\nMessage: %s", matches[1], matches[2], matches[3])
+// runUnrealAutomationTool runs the Unreal Automation Tool and retries the run if it fails with the output matching a transient failure rule
+func runUnrealAutomationTool(args []string, options UnrealAutomationToolOptions) (result UnrealAutomationToolResult, err error) {
+	logPath := options.LogPath
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && logPath != "" {
+			// Keep the log of the failed attempt
+			options.LogPath = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(logPath, filepath.Ext(logPath)), attempt, filepath.Ext(logPath))
+		}
+
+		result, err = runUnrealAutomationToolAttempt(args, options, attempt)
+		result.Attempts = attempt
+		if err == nil || result.Retry == nil || attempt >= options.MaxAttempts {
+			return
+		}
+
+		Logger.Warningf("AutomationTool failed with a transient failure %s, retrying in %s (attempt %d of %d): %v", result.Retry.Name, options.RetryDelay, attempt+1, options.MaxAttempts, err)
+
+		if result.Retry.CleanIntermediate {
+			if err1 := cleanIntermediateDirs(options.IntermediateDirs); err1 != nil {
+				Logger.Errorf("failed to clean intermediate directories: %v", err1)
 			}
 		}
 
+		time.Sleep(options.RetryDelay)
 	}
 }
 
-// runUnrealAutomationTool runs the Unreal Automation Tool, logs its output to stdout and waits for it to exit completing the automation command then returns
-func runUnrealAutomationTool(args []string) (result UnrealAutomationToolResult, err error) {
-	result = UnrealAutomationToolResult{
-		ExitCode: 0,
-		Warnings: nil,
-		Errors:   nil,
-	}
+// runUnrealAutomationToolAttempt runs the Unreal Automation Tool, logs its output to stdout and waits for it to exit completing the automation command then returns.
+// The process tree is killed if the run exceeds the timeout, produces no output for the inactivity timeout or the context is cancelled.
+func runUnrealAutomationToolAttempt(args []string, options UnrealAutomationToolOptions, attempt int) (result UnrealAutomationToolResult, err error) {
+	var parser = NewUnrealLogParser()
+	var metrics = &buildMetricsParser{}
 
-	if _, err = os.Stat(uatPath); errors.Is(err, os.ErrNotExist) {
-		return result, fmt.Errorf("no AutomationTool binary found at %s", uatPath)
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	uatDir := filepath.Dir(uatPath)
-	cmd := exec.Command(uatPath, args...)
-	cmd.Dir = uatDir
-	rd, err := cmd.StdoutPipe()
-	if err != nil {
-		return result, fmt.Errorf("failed to attach to the AutomationTool stdout pipe: %v", err)
+	logPath := options.LogPath
+	if logPath == "" {
+		logPath = "uat.log"
 	}
 
-	var exitCode = -1
-
-	var wg sync.WaitGroup
-
-	go func() {
-		wg.Add(1)
+	run := &JobRunReport{Tool: "AutomationTool", Attempt: attempt, Started: time.Now().UTC(), ExitCode: -1}
+	if len(args) > 0 {
+		run.Command = args[0]
+	}
 
-		logFile, err := os.Create("uat.log")
-		if err != nil {
-			Logger.Errorf("failed to create uat.log: %s", err)
+	defer func() {
+		if options.Report == nil {
+			return
 		}
-
-		defer func(f *os.File) {
-			err := f.Close()
-			if err != nil {
-				Logger.Errorf("failed to close uat.log: %s", err)
-			}
-		}(logFile)
-
-		defer func(rd io.ReadCloser) {
-			err := rd.Close()
-			if err != nil {
-				Logger.Errorf("failed to close rd: %s", err)
-			}
-		}(rd)
-
-		reader := bufio.NewReader(rd)
-		for {
-			line, err := reader.ReadString('\n')
-			s := strings.ReplaceAll(strings.ReplaceAll(line, "\\", "/"), "\r\n", "\n")
-			Logger.Infof(line)
-			_, err1 := logFile.WriteString(line)
-			if err1 != nil {
-				Logger.Errorf("failed to write to uat.log: %s", err)
-			}
-			if strings.HasPrefix(s, "AutomationTool exiting with ExitCode") {
-				i0 := strings.Index(s, "ExitCode=")
-				i1 := strings.Index(s, " (")
-				if i0 > 0 && i1 > 0 {
-					code := s[i0+9 : i1]
-					if code != "0" {
-						if exitCode, err = strconv.Atoi(code); err != nil {
-							Logger.Errorf("failed to convert code %s to int: %v", code, err)
-						}
-					} else {
-						exitCode = 0
-					}
-					err = io.EOF
-				}
-			}
-
-			// Check for errors and exit conditions.
-			if err != nil {
-				if err == io.EOF {
-					Logger.Infof("AutomationTool process has exited")
-				} else {
-					Logger.Errorf("failed to read the UAT process pipe: %v", err)
-				}
-
-				// Flush the log file.
-				if err := logFile.Sync(); err != nil {
-					Logger.Errorf("failed to sync uat.log: %s", err)
-				}
-
-				// Close the log file.
-				if err := logFile.Close(); err != nil {
-					Logger.Errorf("failed to close uat.log: %s", err)
-				}
-
-				// Open the log file again.
-				logFile, err := os.Open("uat.log")
-				if err != nil {
-					Logger.Errorf("failed to open uat.log: %s", err)
-				}
-
-				// Scan the log file for errors.
-				scanner := bufio.NewScanner(logFile)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if strings.Contains(line, "Warning:") {
-						result.Warnings = append(result.Warnings, line)
-					}
-					if strings.Contains(line, "Error:") {
-						result.Errors = append(result.Errors, line)
-					}
-				}
-
-				// Check for errors.
-				if err := scanner.Err(); err != nil {
-					Logger.Errorf("failed to read uat.log: %s", err)
-				}
-
-				// Close the log file.
-				err = logFile.Close()
-				if err != nil {
-					Logger.Errorf("failed to close uat.log: %s", err)
-				}
-
-				result.Process()
-
-				wg.Done()
-
-				return // Exit goroutine.
+		run.Duration = time.Since(run.Started).Seconds()
+		run.ExitCode = result.ExitCode
+		run.Metrics = result.Metrics
+		run.Crashes = result.Crashes
+		if err != nil {
+			run.Error = err.Error()
+			if result.Retry != nil {
+				run.Transient = result.Retry.Name
 			}
 		}
+		options.Report(run, result)
 	}()
 
-	if err := cmd.Start(); err != nil {
-		return result, fmt.Errorf("cmd.Start() error: %v", err)
+	process, err := runProcess(ctx, ProcessOptions{
+		Name:              "AutomationTool",
+		Path:              uatPath,
+		Args:              args,
+		Env:               options.Env,
+		Timeout:           options.Timeout,
+		InactivityTimeout: options.InactivityTimeout,
+		LogPath:           logPath,
+		OnLine: func(stream string, line string) {
+			parser.ParseLine(line)
+			metrics.ParseLine(line, time.Now())
+			if options.Output != nil {
+				options.Output(line)
+			}
+		},
+	})
+
+	result.Entries = parser.Entries()
+	result.Warnings, result.Errors = parser.Messages()
+	result.Tail = process.Tail
+	result.Metrics = metrics.Metrics(time.Now())
+	result.ExitCode = process.ExitCode
+
+	// The exit summary line tells the automation result when the launch script hides the exit code
+	if parser.ExitCode != nil && result.ExitCode == 0 {
+		result.ExitCode = *parser.ExitCode
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return result, fmt.Errorf("cmd.Wait() error: %v", err)
+	if err != nil || result.ExitCode != 0 {
+		result.Crashes = collectUnrealCrashes(result.Entries, options.CrashDirs, run.Started)
 	}
 
-	if exitCode > 0 {
-		return result, fmt.Errorf("UAT exit code: %d", exitCode)
+	// Runs killed for a timeout or a cancellation are not retried
+	if (err != nil || result.ExitCode != 0) && process.Killed == "" {
+		result.Retry = matchTransientFailure(options.RetryRules, result.Entries, result.Tail)
 	}
 
-	wg.Wait()
+	if process.Killed != "" || (err != nil && process.ExitCode < 0) {
+		// Not started, killed by the runner or by a signal
+		return result, fmt.Errorf("%v%s", err, crashSummary(result.Crashes))
+	}
+
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("UAT exit code: %d%s", result.ExitCode, crashSummary(result.Crashes))
+	}
 
 	return result, nil
 }
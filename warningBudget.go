@@ -0,0 +1,408 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWarningBaselineDir = "job-baselines"
+	warningBudgetActionFail   = "fail"
+	warningBudgetActionFlag   = "flag"
+	warningBudgetListLimit    = 50 // Maximum number of new warnings listed in the failure message
+)
+
+var (
+	// Absolute paths in the warning messages, e.g. "C:/Build/Metaverse/Content/A.uasset" or "/home/builder/Metaverse/Source/A.cpp"
+	warningPathRegexp = regexp.MustCompile(`(?:[A-Za-z]:)?(?:/[^/\s'"()\[\]]+){2,}`)
+
+	// Numbers in the warning messages, e.g. sizes, counts and addresses
+	warningNumberRegexp = regexp.MustCompile(`(?:0x[0-9A-Fa-f]+|\d+)`)
+
+	warningRootMu     sync.Mutex
+	warningRootDir    string         // Project directory the root regexp was compiled for
+	warningRootRegexp *regexp.Regexp // Project directory prefix of the paths in the warning messages
+)
+
+// QualityGateConfig configures the warning budget of release jobs compared to the previous successful release
+type QualityGateConfig struct {
+	Disabled       bool           `json:"disabled,omitempty"`
+	Action         string         `json:"action,omitempty"`         // "flag" reports the exceeded budget, "fail" also fails the job, default flag
+	Budgets        map[string]int `json:"budgets,omitempty"`        // Allowed increase of the count per category, e.g. {"Warning:Cook": 10}, "*" applies to other categories, default 0
+	MaxNewWarnings *int           `json:"maxNewWarnings,omitempty"` // Allowed number of distinct warnings not present in the baseline, unlimited if not set
+	BaselineDir    string         `json:"baselineDir,omitempty"`    // Directory keeping the baselines, default job-baselines in the working directory
+	ApiBaseline    bool           `json:"apiBaseline,omitempty"`    // Fetch the baseline from the API if there is no local one
+}
+
+// WarningBaseline are the warning and error counts of the last successful release of the app, platform and deployment
+type WarningBaseline struct {
+	JobId        string         `json:"jobId"`
+	ReleaseId    string         `json:"releaseId,omitempty"`
+	Version      string         `json:"version,omitempty"`
+	Time         time.Time      `json:"time"`
+	Counts       map[string]int `json:"counts"`       // Number of occurrences per category, e.g. "Warning:Cook"
+	Fingerprints []string       `json:"fingerprints"` // Fingerprints of the distinct warnings
+}
+
+// WarningBudgetCategory is the count of a category compared to the baseline
+type WarningBudgetCategory struct {
+	Category string `json:"category"`
+	Baseline int    `json:"baseline"`
+	Count    int    `json:"count"`
+	Budget   int    `json:"budget"` // Allowed increase
+	Exceeded bool   `json:"exceeded,omitempty"`
+}
+
+// WarningBudgetResult is the result of the quality gate reported with the job log
+type WarningBudgetResult struct {
+	BaselineJobId string                   `json:"baselineJobId,omitempty"` // Empty if there was no baseline
+	Categories    []*WarningBudgetCategory `json:"categories,omitempty"`
+	NewWarnings   []string                 `json:"newWarnings,omitempty"` // Formatted warnings not present in the baseline
+	Exceeded      bool                     `json:"exceeded"`
+	Action        string                   `json:"action"`
+}
+
+// checkWarningBudget compares the warnings of the job runs with the baseline and reports the result with the job log,
+// returns an error listing the new warnings if the budget is exceeded and the gate is configured to fail the job
+func checkWarningBudget(job JobMetadata) error {
+	if config.QualityGate.Disabled {
+		return nil
+	}
+
+	r := currentJobReport(job)
+	if r == nil {
+		return nil
+	}
+
+	baseline, err := loadWarningBaseline(job)
+	if err != nil {
+		Logger.Warningf("failed to load warning baseline: %v", err)
+	}
+
+	result := evaluateWarningBudget(r.mergedEntries(), baseline)
+	r.setWarningBudget(result)
+
+	if baseline == nil {
+		Logger.Infof("no warning baseline for %s, the quality gate is skipped", warningBaselineKey(job))
+		return nil
+	}
+
+	if !result.Exceeded {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("warning budget exceeded compared to the release of job " + baseline.JobId)
+	for _, c := range result.Categories {
+		if c.Exceeded {
+			b.WriteString(fmt.Sprintf("\n%s: %d -> %d (budget +%d)", c.Category, c.Baseline, c.Count, c.Budget))
+		}
+	}
+	if len(result.NewWarnings) > 0 {
+		b.WriteString(fmt.Sprintf("\nnew warnings (%d):", len(result.NewWarnings)))
+		for i, w := range result.NewWarnings {
+			if i == warningBudgetListLimit {
+				b.WriteString(fmt.Sprintf("\n... and %d more", len(result.NewWarnings)-i))
+				break
+			}
+			b.WriteString("\n" + w)
+		}
+	}
+
+	if result.Action == warningBudgetActionFail {
+		return fmt.Errorf("%s", b.String())
+	}
+
+	Logger.Warningf("%s", b.String())
+
+	return nil
+}
+
+// evaluateWarningBudget compares the entries with the baseline using the configured budgets, nothing is exceeded without a baseline
+func evaluateWarningBudget(entries []*LogEntry, baseline *WarningBaseline) *WarningBudgetResult {
+	result := &WarningBudgetResult{Action: config.QualityGate.Action}
+	if result.Action == "" {
+		result.Action = warningBudgetActionFlag
+	}
+
+	counts := warningCounts(entries)
+	if baseline == nil {
+		return result
+	}
+	result.BaselineJobId = baseline.JobId
+
+	var categories []string
+	for c := range counts {
+		categories = append(categories, c)
+	}
+	for c := range baseline.Counts {
+		if _, ok := counts[c]; !ok {
+			categories = append(categories, c)
+		}
+	}
+	sort.Strings(categories)
+
+	for _, c := range categories {
+		budget, ok := config.QualityGate.Budgets[c]
+		if !ok {
+			budget = config.QualityGate.Budgets["*"]
+		}
+
+		category := &WarningBudgetCategory{Category: c, Baseline: baseline.Counts[c], Count: counts[c], Budget: budget}
+		category.Exceeded = category.Count > category.Baseline+budget
+		result.Exceeded = result.Exceeded || category.Exceeded
+		result.Categories = append(result.Categories, category)
+	}
+
+	known := map[string]bool{}
+	for _, f := range baseline.Fingerprints {
+		known[f] = true
+	}
+	for _, e := range entries {
+		if e.Severity == LogSeverityWarning && !known[warningFingerprint(e)] {
+			result.NewWarnings = append(result.NewWarnings, e.String())
+		}
+	}
+
+	if config.QualityGate.MaxNewWarnings != nil && len(result.NewWarnings) > *config.QualityGate.MaxNewWarnings {
+		result.Exceeded = true
+	}
+
+	return result
+}
+
+// saveWarningBaseline stores the warning counts of the successful release job as the baseline of the next releases
+func saveWarningBaseline(job JobMetadata) error {
+	if config.QualityGate.Disabled {
+		return nil
+	}
+
+	r := currentJobReport(job)
+	if r == nil {
+		return nil
+	}
+
+	// The baseline is kept when the gate only flagged the exceeded budget, otherwise the next release would be compared with the regressed one
+	if r.warningBudgetExceeded() {
+		Logger.Warningf("warning budget exceeded, the baseline %s is not updated", warningBaselineKey(job))
+		return nil
+	}
+
+	entries := r.mergedEntries()
+	baseline := WarningBaseline{Time: time.Now().UTC(), Counts: warningCounts(entries), Fingerprints: []string{}}
+	if job.Id != nil {
+		baseline.JobId = job.Id.String()
+	}
+	if job.Release != nil {
+		baseline.Version = job.Release.Version
+		if job.Release.Id != nil {
+			baseline.ReleaseId = job.Release.Id.String()
+		}
+	}
+	for _, e := range entries {
+		if e.Severity == LogSeverityWarning {
+			baseline.Fingerprints = append(baseline.Fingerprints, warningFingerprint(e))
+		}
+	}
+
+	b, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize json: %v", err)
+	}
+
+	dir := warningBaselineDir()
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create a directory %s: %v", dir, err)
+	}
+
+	path := filepath.Join(dir, warningBaselineKey(job)+".json")
+	if err = os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	Logger.Infof("saved warning baseline %s", path)
+
+	return nil
+}
+
+// loadWarningBaseline reads the local baseline of the job app, platform and deployment, falls back to the API if configured, returns nil if there is none
+func loadWarningBaseline(job JobMetadata) (*WarningBaseline, error) {
+	path := filepath.Join(warningBaselineDir(), warningBaselineKey(job)+".json")
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if config.QualityGate.ApiBaseline {
+			return fetchWarningBaseline(job)
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var baseline WarningBaseline
+	if err = json.Unmarshal(b, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return &baseline, nil
+}
+
+// fetchWarningBaseline requests the baseline of the previous successful release of the app, platform and deployment from the API, returns nil if there is none
+func fetchWarningBaseline(job JobMetadata) (*WarningBaseline, error) {
+	if job.Release == nil || job.Release.AppId == nil {
+		return nil, nil
+	}
+
+	reqUrl := fmt.Sprintf("%s/apps/%s/releases/warning-baseline?platform=%s&deployment=%s", api2Url, job.Release.AppId.String(), job.Platform, job.Deployment)
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %s", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %s", err)
+	}
+
+	defer func(body io.ReadCloser) {
+		if err := body.Close(); err != nil {
+			Logger.Errorf("failed to close resp body: %v", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to fetch warning baseline, status code: %d, content: %s", resp.StatusCode, string(body))
+	}
+
+	var container struct {
+		Data *WarningBaseline `json:"data"`
+	}
+	if err = json.Unmarshal(body, &container); err != nil {
+		return nil, fmt.Errorf("failed to parse warning baseline json: %s", err)
+	}
+
+	return container.Data, nil
+}
+
+// warningBaselineDir returns the directory keeping the baselines
+func warningBaselineDir() string {
+	if config.QualityGate.BaselineDir != "" {
+		return config.QualityGate.BaselineDir
+	}
+
+	return defaultWarningBaselineDir
+}
+
+// warningBaselineKey identifies the baseline of the job app, platform and deployment
+func warningBaselineKey(job JobMetadata) string {
+	app := "unknown"
+	if job.Release != nil {
+		if job.Release.AppId != nil {
+			app = job.Release.AppId.String()
+		} else if job.Release.AppName != "" {
+			app = job.Release.AppName
+		}
+	}
+
+	return fmt.Sprintf("%s-%s-%s", app, job.Platform, job.Deployment)
+}
+
+// warningCounts counts the occurrences of the entries per severity and category, e.g. "Warning:Cook" or "Warning:Compiler"
+func warningCounts(entries []*LogEntry) map[string]int {
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[e.Severity+":"+warningCategory(e)] += e.Count
+	}
+
+	return counts
+}
+
+// warningCategory returns the log category of the entry, the kind if it has none
+func warningCategory(e *LogEntry) string {
+	if e.Category != "" {
+		return e.Category
+	}
+
+	return e.Kind
+}
+
+// warningFingerprint identifies the warning across jobs, the location in the file, the checkout directory and the numbers in the message are ignored
+// so the warning keeps its fingerprint when unrelated changes move it or the job runs on another runner
+func warningFingerprint(e *LogEntry) string {
+	h := sha1.Sum([]byte(strings.Join([]string{e.Severity, warningCategory(e), e.Code, warningRelativePath(e.File), normalizeWarningMessage(e.Message)}, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// warningRelativePath returns the path relative to the project directory, paths outside the project are relative to the engine directory if they are in one
+func warningRelativePath(file string) string {
+	if file == "" {
+		return ""
+	}
+
+	file = warningSlashPath(file)
+	if projectDir != "" {
+		root := warningSlashPath(projectDir) + "/"
+		if len(file) > len(root) && strings.EqualFold(file[:len(root)], root) {
+			return file[len(root):]
+		}
+	}
+
+	if i := strings.LastIndex(file, "/Engine/"); i >= 0 {
+		return file[i+1:]
+	}
+
+	return file
+}
+
+// warningSlashPath cleans the path using forward slashes whatever the platform the log was written on
+func warningSlashPath(file string) string {
+	return path.Clean(strings.ReplaceAll(file, "\\", "/"))
+}
+
+// warningProjectRoot returns the regexp matching the project directory prefix, compiled again only when the project directory changes
+func warningProjectRoot() *regexp.Regexp {
+	warningRootMu.Lock()
+	defer warningRootMu.Unlock()
+
+	if warningRootRegexp == nil || warningRootDir != projectDir {
+		warningRootDir = projectDir
+		warningRootRegexp = regexp.MustCompile(`(?i)` + regexp.QuoteMeta(warningSlashPath(projectDir)+"/"))
+	}
+
+	return warningRootRegexp
+}
+
+// normalizeWarningMessage replaces the absolute paths by their project relative form and the numbers by a placeholder
+func normalizeWarningMessage(message string) string {
+	message = strings.ReplaceAll(message, "\\", "/")
+	if projectDir != "" {
+		// The project directory may contain spaces which end the matched paths
+		message = warningProjectRoot().ReplaceAllString(message, "")
+	}
+	message = warningPathRegexp.ReplaceAllStringFunc(message, warningRelativePath)
+
+	return warningNumberRegexp.ReplaceAllString(message, "#")
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestWarningFingerprint(t *testing.T) {
+	oldProjectDir := projectDir
+	t.Cleanup(func() {
+		projectDir = oldProjectDir
+	})
+
+	// fingerprint returns the fingerprint of the warning of the job run in the project directory
+	fingerprint := func(dir string, line int, message string) string {
+		projectDir = dir
+		return warningFingerprint(&LogEntry{Severity: LogSeverityWarning, Kind: LogKindCompiler, File: dir + "/Source/Metaverse/Actor.cpp", Line: line, Column: 5, Code: "C4996", Message: message})
+	}
+
+	base := fingerprint("C:/Build Agents/1/Metaverse", 12, "'Foo': was declared deprecated in C:/Build Agents/1/Metaverse/Source/Metaverse/Foo.h(40)")
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		same        bool
+	}{
+		{"moved line", fingerprint("C:/Build Agents/1/Metaverse", 30, "'Foo': was declared deprecated in C:/Build Agents/1/Metaverse/Source/Metaverse/Foo.h(42)"), true},
+		{"another checkout", fingerprint("/home/builder/Metaverse", 12, "'Foo': was declared deprecated in /home/builder/Metaverse/Source/Metaverse/Foo.h(40)"), true},
+		{"windows separators", fingerprint("C:\\Build Agents\\2\\Metaverse", 12, "'Foo': was declared deprecated in C:\\Build Agents\\2\\Metaverse\\Source\\Metaverse\\Foo.h(40)"), true},
+		{"another message", fingerprint("C:/Build Agents/1/Metaverse", 12, "'Bar': was declared deprecated in C:/Build Agents/1/Metaverse/Source/Metaverse/Bar.h(40)"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fingerprint == base; got != tt.same {
+				t.Errorf("same fingerprint = %v, want %v", got, tt.same)
+			}
+		})
+	}
+
+	engine := func(root string) *LogEntry {
+		return &LogEntry{Severity: LogSeverityWarning, Kind: LogKindCompiler, File: root + "/Engine/Source/Runtime/Core/Public/Misc/AssertionMacros.h", Line: 100, Message: "unused variable 'x'"}
+	}
+	if warningFingerprint(engine("/opt/UE_5.1")) != warningFingerprint(engine("D:/Epic Games/UE_5.1")) {
+		t.Error("engine warnings of different installations have different fingerprints")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// processWatchdog kills the process tree when the wall-clock timeout expires or when the process produces no output for the inactivity timeout
+type processWatchdog struct {
+	name       string        // Process name used in logs
+	timeout    time.Duration // Wall-clock timeout, disabled if zero
+	inactivity time.Duration // Inactivity timeout, disabled if zero
+	lastOutput int64         // Time of the last output in unix nanoseconds
+	startedAt  time.Time
+	done       chan struct{}
+	stopOnce   sync.Once
+
+	mu     sync.Mutex
+	reason string // Reason the process was killed, empty if not killed
+}
+
+// newProcessWatchdog creates a stopped watchdog
+func newProcessWatchdog(name string, timeout time.Duration, inactivity time.Duration) *processWatchdog {
+	return &processWatchdog{name: name, timeout: timeout, inactivity: inactivity, done: make(chan struct{})}
+}
+
+// Start watches the started process until Stop is called
+func (w *processWatchdog) Start(cmd *exec.Cmd) {
+	w.startedAt = time.Now()
+	w.Touch()
+
+	if w.timeout <= 0 && w.inactivity <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(watchdogInterval(w.timeout, w.inactivity))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case now := <-ticker.C:
+				var reason string
+				if w.timeout > 0 && now.Sub(w.startedAt) > w.timeout {
+					reason = fmt.Sprintf("%s timed out after %s", w.name, w.timeout)
+				} else if w.inactivity > 0 && now.Sub(time.Unix(0, atomic.LoadInt64(&w.lastOutput))) > w.inactivity {
+					reason = fmt.Sprintf("%s hung, no output for more than %s", w.name, w.inactivity)
+				}
+
+				if reason != "" {
+					w.mu.Lock()
+					w.reason = reason
+					w.mu.Unlock()
+
+					Logger.Errorf("%s, killing the process tree", reason)
+					if err := killProcessTree(cmd); err != nil {
+						Logger.Errorf("failed to kill the %s process tree: %v", w.name, err)
+					}
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Touch records the process output
+func (w *processWatchdog) Touch() {
+	atomic.StoreInt64(&w.lastOutput, time.Now().UnixNano())
+}
+
+// Stop stops watching the process
+func (w *processWatchdog) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+// Reason returns the reason the process was killed, empty if it was not
+func (w *processWatchdog) Reason() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reason
+}
+
+// watchdogInterval returns the check interval, frequent enough for the shortest configured timeout
+func watchdogInterval(timeouts ...time.Duration) time.Duration {
+	interval := 10 * time.Second
+	for _, t := range timeouts {
+		if t > 0 && t/10 < interval {
+			interval = t / 10
+		}
+	}
+
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+
+	return interval
+}
+
+// lineTail keeps the last lines of the process output
+type lineTail struct {
+	mu    sync.Mutex
+	lines []string
+	size  int
+}
+
+// newLineTail creates a tail keeping at most size lines
+func newLineTail(size int) *lineTail {
+	return &lineTail{size: size}
+}
+
+// Add appends the line dropping the oldest one if full
+func (t *lineTail) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	line = strings.TrimRight(line, "\r\n")
+	if len(t.lines) == t.size {
+		t.lines = append(t.lines[1:], line)
+	} else {
+		t.lines = append(t.lines, line)
+	}
+}
+
+// Lines returns a copy of the kept lines
+func (t *lineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]string(nil), t.lines...)
+}
+
+// lastLines returns at most n last lines
+func lastLines(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+
+	return lines
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"archive/zip"
+	"compress/flate"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 func unzip(src, dst string) error {
@@ -80,3 +83,99 @@ func unzip(src, dst string) error {
 
 	return nil
 }
+
+// zipCompressionLevel is the deflate level used for release archives, pinned so identical inputs always produce identical bytes
+const zipCompressionLevel = flate.BestSpeed
+
+// zipModTime is the modification time written to every release archive entry (the earliest time representable in a zip header)
+var zipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// zipFiles writes a deterministic zip archive to dst, files maps paths on disk to their paths in the archive.
+// Entries are sorted by their path in the archive, timestamps are normalized and permissions are reduced to 0644 or 0755,
+// so the same set of files always produces a byte-identical archive.
+func zipFiles(dst string, files map[string]string) (err error) {
+	var names = make([]string, 0, len(files))
+	var paths = make(map[string]string, len(files))
+	for path, name := range files {
+		name = filepath.ToSlash(name)
+		if _, ok := paths[name]; ok {
+			return fmt.Errorf("duplicate archive path: %s", name)
+		}
+		paths[name] = path
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create a zip file: %v", err)
+	}
+	defer func() {
+		if err1 := out.Close(); err1 != nil && err == nil {
+			err = fmt.Errorf("failed to close a zip file: %v", err1)
+		}
+	}()
+
+	w := zip.NewWriter(out)
+	w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, zipCompressionLevel)
+	})
+
+	for _, name := range names {
+		if err = zipFile(w, paths[name], name); err != nil {
+			return err
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize a zip file: %v", err)
+	}
+
+	return nil
+}
+
+// zipFile adds a single file to the zip writer using a normalized header
+func zipFile(w *zip.Writer, path string, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open a file %s: %v", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			Logger.Errorf("failed to close a file %s: %v", path, err)
+		}
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat a file %s: %v", path, err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", path)
+	}
+
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: zipModTime,
+	}
+
+	// Keep only the executable bit, everything else gets the same permissions
+	if fi.Mode().Perm()&0111 != 0 {
+		header.SetMode(0755)
+	} else {
+		header.SetMode(0644)
+	}
+
+	zw, err := w.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to create a zip entry %s: %v", name, err)
+	}
+
+	if _, err = io.Copy(zw, f); err != nil {
+		return fmt.Errorf("failed to compress a file %s: %v", path, err)
+	}
+
+	return nil
+}